@@ -1,10 +0,0 @@
-package main
-
-var (
-    incr uint32
-)
-
-func nextid() int {
-    incr++
-    return int(incr)
-}
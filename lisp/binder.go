@@ -0,0 +1,428 @@
+package lisp
+
+import (
+    `fmt`
+    `math`
+    `math/big`
+    `reflect`
+)
+
+var (
+    _ValueType = reflect.TypeOf((*Value)(nil)).Elem()
+    _ErrorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+type _Binder struct {
+    name string
+    argc int
+    retv bool
+    rerr bool
+    args []reflect.Type
+    rest reflect.Type
+    proc reflect.Value
+}
+
+func BindFunc(name string, fn interface{}) func([]Value) Value {
+    vf := reflect.ValueOf(fn)
+    vt := reflect.TypeOf(fn)
+
+    /* only functions can be bound */
+    if vt == nil || vt.Kind() != reflect.Func || vf.IsNil() {
+        panic(fmt.Sprintf("binder: %s: object is not a function: %T", name, fn))
+    }
+
+    /* create the binder */
+    bd := &_Binder {
+        name: name,
+        argc: vt.NumIn(),
+        proc: vf,
+    }
+
+    /* variadic functions collect the remaining arguments into the last slice */
+    if vt.IsVariadic() {
+        bd.argc--
+        bd.rest = vt.In(bd.argc).Elem()
+    }
+
+    /* check for every parameter */
+    for i := 0; i < bd.argc; i++ {
+        if at := vt.In(i); !bindable(at) {
+            panic(fmt.Sprintf("binder: %s: unsupported parameter type: %s", name, at))
+        } else {
+            bd.args = append(bd.args, at)
+        }
+    }
+
+    /* check for the variadic parameter */
+    if bd.rest != nil && !bindable(bd.rest) {
+        panic(fmt.Sprintf("binder: %s: unsupported parameter type: %s", name, bd.rest))
+    }
+
+    /* the function may return a value, an error, or both of them in this order */
+    switch vt.NumOut() {
+        case 0  : break
+        case 1  : bd.rerr = vt.Out(0) == _ErrorType; bd.retv = !bd.rerr
+        case 2  : bd.rerr = vt.Out(1) == _ErrorType; bd.retv = true
+        default : panic(fmt.Sprintf("binder: %s: function returns too many values: %s", name, vt))
+    }
+
+    /* the second result must be an error */
+    if vt.NumOut() == 2 && !bd.rerr {
+        panic(fmt.Sprintf("binder: %s: the second result must be an error: %s", name, vt))
+    }
+
+    /* check for the result type */
+    if bd.retv && !bindable(vt.Out(0)) {
+        panic(fmt.Sprintf("binder: %s: unsupported result type: %s", name, vt.Out(0)))
+    }
+
+    /* all done */
+    return bd.call
+}
+
+func RegisterFunc(name string, fn interface{}) {
+    RegisterIntrinsic(name, BindFunc(name, fn))
+}
+
+func (self *Interpreter) RegisterFunc(name string, fn interface{}) {
+    self.RegisterIntrinsic(name, BindFunc(name, fn))
+}
+
+func (self *_Binder) call(args []Value) Value {
+    self.arity(len(args))
+    argv := make([]reflect.Value, len(args))
+
+    /* convert all the arguments */
+    for i, v := range args {
+        if i < self.argc {
+            argv[i] = self.value(v, self.args[i])
+        } else {
+            argv[i] = self.value(v, self.rest)
+        }
+    }
+
+    /* call the function */
+    ret := self.proc.Call(argv)
+    nrv := len(ret)
+
+    /* Go errors are raised as errors */
+    if self.rerr && !ret[nrv - 1].IsNil() {
+        if err, ok := ret[nrv - 1].Interface().(*LispError); ok {
+            panic(err)
+        } else {
+            panic(MakeError(ErrRuntime, self.name + ": " + ret[nrv - 1].Interface().(error).Error()))
+        }
+    }
+
+    /* convert the result if any */
+    if !self.retv {
+        return nil
+    } else {
+        return self.result(ret[0])
+    }
+}
+
+func (self *_Binder) arity(argc int) {
+    switch {
+        case self.rest != nil && argc >= self.argc : return
+        case self.rest == nil && argc == self.argc : return
+        case self.rest != nil && self.argc == 1    : panic(MakeError(ErrArity, self.name + ": proc requires at least 1 argument"))
+        case self.rest != nil                      : panic(MakeError(ErrArity, fmt.Sprintf("%s: proc requires at least %d arguments", self.name, self.argc)))
+        case self.argc == 0                        : panic(MakeError(ErrArity, self.name + ": proc takes no arguments"))
+        case self.argc == 1                        : panic(MakeError(ErrArity, self.name + ": proc takes exact 1 argument"))
+        default                                    : panic(MakeError(ErrArity, fmt.Sprintf("%s: proc takes exact %d arguments", self.name, self.argc)))
+    }
+}
+
+func (self *_Binder) error(msg string, v Value) *LispError {
+    return MakeError(ErrType, self.name + ": " + msg, v)
+}
+
+func bindable(vt reflect.Type) bool {
+    if vt.Implements(_ValueType) || vt.Kind() == reflect.Interface {
+        return true
+    }
+
+    /* check for Go types */
+    switch vt.Kind() {
+        case reflect.Int        : return true
+        case reflect.Int8       : return true
+        case reflect.Int16      : return true
+        case reflect.Int32      : return true
+        case reflect.Int64      : return true
+        case reflect.Uint       : return true
+        case reflect.Uint8      : return true
+        case reflect.Uint16     : return true
+        case reflect.Uint32     : return true
+        case reflect.Uint64     : return true
+        case reflect.Float32    : return true
+        case reflect.Float64    : return true
+        case reflect.Complex64  : return true
+        case reflect.Complex128 : return true
+        case reflect.Bool       : return true
+        case reflect.String     : return true
+        case reflect.Slice      : return bindable(vt.Elem())
+        default                 : return false
+    }
+}
+
+/** Argument Conversion **/
+
+func nilable(vt reflect.Type) bool {
+    switch vt.Kind() {
+        case reflect.Ptr       : return true
+        case reflect.Interface : return true
+        default                : return false
+    }
+}
+
+func (self *_Binder) value(v Value, vt reflect.Type) reflect.Value {
+    if vt.Implements(_ValueType) || vt.Kind() == reflect.Interface {
+        return self.object(v, vt)
+    }
+
+    /* convert to Go types */
+    switch vt.Kind() {
+        case reflect.Int        : fallthrough
+        case reflect.Int8       : fallthrough
+        case reflect.Int16      : fallthrough
+        case reflect.Int32      : fallthrough
+        case reflect.Int64      : return self.int(v, vt)
+        case reflect.Uint       : fallthrough
+        case reflect.Uint8      : fallthrough
+        case reflect.Uint16     : fallthrough
+        case reflect.Uint32     : fallthrough
+        case reflect.Uint64     : return self.uint(v, vt)
+        case reflect.Float32    : fallthrough
+        case reflect.Float64    : return self.float(v, vt)
+        case reflect.Complex64  : fallthrough
+        case reflect.Complex128 : return self.complex(v, vt)
+        case reflect.Bool       : return self.bool(v, vt)
+        case reflect.String     : return self.string(v, vt)
+        case reflect.Slice      : return self.slice(v, vt)
+        default                 : panic("binder: unreachable")
+    }
+}
+
+func (self *_Binder) object(v Value, vt reflect.Type) reflect.Value {
+    if v == nil && nilable(vt) {
+        return reflect.Zero(vt)
+    } else if v == nil || !reflect.TypeOf(v).AssignableTo(vt) {
+        panic(self.error(fmt.Sprintf("object is not of type %s", vt), v))
+    } else {
+        return reflect.ValueOf(v)
+    }
+}
+
+func (self *_Binder) integer(v Value) *big.Int {
+    switch iv := v.(type) {
+        case Int     : return big.NewInt(int64(iv))
+        case *BigInt : return iv.Int()
+        default      : panic(self.error("object is not an integer", v))
+    }
+}
+
+func (self *_Binder) int(v Value, vt reflect.Type) reflect.Value {
+    iv := self.integer(v)
+    rv := reflect.New(vt).Elem()
+
+    /* check for range */
+    if !iv.IsInt64() || rv.OverflowInt(iv.Int64()) {
+        panic(self.error("integer is out of range for " + vt.String(), v))
+    }
+
+    /* all done */
+    rv.SetInt(iv.Int64())
+    return rv
+}
+
+func (self *_Binder) uint(v Value, vt reflect.Type) reflect.Value {
+    iv := self.integer(v)
+    rv := reflect.New(vt).Elem()
+
+    /* check for range */
+    if !iv.IsUint64() || rv.OverflowUint(iv.Uint64()) {
+        panic(self.error("integer is out of range for " + vt.String(), v))
+    }
+
+    /* all done */
+    rv.SetUint(iv.Uint64())
+    return rv
+}
+
+func (self *_Binder) float(v Value, vt reflect.Type) reflect.Value {
+    if nv, ok := v.(Numerical); !ok || nv.Kind() == NumComplex {
+        panic(self.error("object is not a real number", v))
+    } else {
+        rv := reflect.New(vt).Elem()
+        rv.SetFloat(float64(nv.AsFloat()))
+        return rv
+    }
+}
+
+func (self *_Binder) complex(v Value, vt reflect.Type) reflect.Value {
+    if nv, ok := v.(Numerical); !ok {
+        panic(self.error("object is not a number", v))
+    } else {
+        rv := reflect.New(vt).Elem()
+        rv.SetComplex(complex128(nv.AsComplex()))
+        return rv
+    }
+}
+
+func (self *_Binder) bool(v Value, vt reflect.Type) reflect.Value {
+    if bv, ok := v.(Bool); !ok {
+        panic(self.error("object is not a boolean", v))
+    } else {
+        rv := reflect.New(vt).Elem()
+        rv.SetBool(bool(bv))
+        return rv
+    }
+}
+
+func (self *_Binder) string(v Value, vt reflect.Type) reflect.Value {
+    if sv, ok := v.(String); !ok {
+        panic(self.error("object is not a string", v))
+    } else {
+        rv := reflect.New(vt).Elem()
+        rv.SetString(string(sv))
+        return rv
+    }
+}
+
+func (self *_Binder) slice(v Value, vt reflect.Type) reflect.Value {
+    var ok bool
+    var sl *List
+    var rv reflect.Value
+
+    /* must be a list */
+    if sl, ok = AsList(v); !ok {
+        panic(self.error("object is not a list", v))
+    }
+
+    /* convert every element */
+    for rv = reflect.MakeSlice(vt, 0, 0); ok && sl != nil; sl, ok = AsList(sl.Cdr) {
+        rv = reflect.Append(rv, self.value(sl.Car, vt.Elem()))
+    }
+
+    /* must be a proper list */
+    if !ok {
+        panic(self.error("object is not a proper list", v))
+    } else {
+        return rv
+    }
+}
+
+/** Result Conversion **/
+
+func (self *_Binder) result(rv reflect.Value) Value {
+    switch rv.Kind() {
+        case reflect.Int        : fallthrough
+        case reflect.Int8       : fallthrough
+        case reflect.Int16      : fallthrough
+        case reflect.Int32      : fallthrough
+        case reflect.Int64      : return self.resultInt(rv)
+        case reflect.Uint       : fallthrough
+        case reflect.Uint8      : fallthrough
+        case reflect.Uint16     : fallthrough
+        case reflect.Uint32     : fallthrough
+        case reflect.Uint64     : return self.resultUint(rv)
+        case reflect.Float32    : fallthrough
+        case reflect.Float64    : return self.resultFloat(rv)
+        case reflect.Complex64  : fallthrough
+        case reflect.Complex128 : return self.resultComplex(rv)
+        case reflect.Bool       : return self.resultBool(rv)
+        case reflect.String     : return self.resultString(rv)
+        case reflect.Slice      : return self.resultSlice(rv)
+        case reflect.Ptr        : return self.resultPointer(rv)
+        case reflect.Interface  : return self.resultInterface(rv)
+        default                 : return self.resultObject(rv)
+    }
+}
+
+func (self *_Binder) resultInt(rv reflect.Value) Value {
+    if rv.Type().Implements(_ValueType) {
+        return self.resultObject(rv)
+    } else {
+        return Int(rv.Int())
+    }
+}
+
+func (self *_Binder) resultUint(rv reflect.Value) Value {
+    if rv.Type().Implements(_ValueType) {
+        return self.resultObject(rv)
+    } else if iv := rv.Uint(); iv > math.MaxInt64 {
+        return MakeInteger(new(big.Int).SetUint64(iv))
+    } else {
+        return Int(iv)
+    }
+}
+
+func (self *_Binder) resultFloat(rv reflect.Value) Value {
+    if rv.Type().Implements(_ValueType) {
+        return self.resultObject(rv)
+    } else {
+        return Float(rv.Float())
+    }
+}
+
+func (self *_Binder) resultComplex(rv reflect.Value) Value {
+    if rv.Type().Implements(_ValueType) {
+        return self.resultObject(rv)
+    } else {
+        return Complex(rv.Complex())
+    }
+}
+
+func (self *_Binder) resultBool(rv reflect.Value) Value {
+    if rv.Type().Implements(_ValueType) {
+        return self.resultObject(rv)
+    } else {
+        return Bool(rv.Bool())
+    }
+}
+
+func (self *_Binder) resultString(rv reflect.Value) Value {
+    if rv.Type().Implements(_ValueType) {
+        return self.resultObject(rv)
+    } else {
+        return String(rv.String())
+    }
+}
+
+func (self *_Binder) resultSlice(rv reflect.Value) Value {
+    var p *List
+    var q *List
+
+    /* slices are converted into lists */
+    for i := 0; i < rv.Len(); i++ {
+        AppendValue(&p, &q, self.result(rv.Index(i)))
+    }
+
+    /* all done */
+    return p
+}
+
+func (self *_Binder) resultPointer(rv reflect.Value) Value {
+    if rv.IsNil() {
+        return nil
+    } else {
+        return self.resultObject(rv)
+    }
+}
+
+func (self *_Binder) resultInterface(rv reflect.Value) Value {
+    if rv.IsNil() {
+        return nil
+    } else {
+        return self.result(rv.Elem())
+    }
+}
+
+func (self *_Binder) resultObject(rv reflect.Value) Value {
+    if vv, ok := rv.Interface().(Value); !ok {
+        panic(MakeError(ErrType, fmt.Sprintf("%s: unsupported result type: %s", self.name, rv.Type())))
+    } else {
+        return vv
+    }
+}
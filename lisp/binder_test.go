@@ -0,0 +1,74 @@
+package lisp
+
+import (
+    `errors`
+    `strconv`
+    `strings`
+    `testing`
+
+    `github.com/stretchr/testify/require`
+)
+
+func TestBinder_Convert(t *testing.T) {
+    it := CreateInterpreter()
+    it.RegisterFunc("fmt", func(a int, b float64) (string, error) { return strconv.Itoa(a) + ":" + strconv.FormatFloat(b, 'f', 2, 64), nil })
+    it.RegisterFunc("join", func(sep string, v []string) string { return strings.Join(v, sep) })
+    it.RegisterFunc("split", func(s string) []string { return strings.Split(s, ",") })
+    it.RegisterFunc("sum", func(v ...int8) (r int) { for _, x := range v { r += int(x) }; return })
+    it.RegisterFunc("head", func(v *List) Value { return v.Car })
+    it.RegisterFunc("big", func() uint64 { return 1 << 63 })
+    it.RegisterFunc("noop", func() {})
+    it.RegisterFunc("id64", func(v uint64) uint64 { return v })
+    it.RegisterFunc("neg", func(v int64) int64 { return -v })
+    for src, exp := range map[string]string {
+        `(fmt 12 3)`                  : `"12:3.00"`,
+        `(fmt 12 2.5)`                : `"12:2.50"`,
+        `(join "-" '("a" "b" "c"))`   : `"a-b-c"`,
+        `(join "-" '())`              : `""`,
+        `(split "a,b")`               : `("a" "b")`,
+        `(sum)`                       : `0`,
+        `(sum 1 2 3)`                 : `6`,
+        `(head '(x y))`               : `x`,
+        `(big)`                       : `9223372036854775808`,
+        `(noop)`                      : `()`,
+        `(id64 (big))`                : `9223372036854775808`,
+        `(id64 18446744073709551615)` : `18446744073709551615`,
+        `(neg -9223372036854775807)`  : `9223372036854775807`,
+    } {
+        v, err := it.Eval(src)
+        require.NoError(t, err, src)
+        require.Equal(t, exp, AsString(v), src)
+    }
+}
+
+func TestBinder_Errors(t *testing.T) {
+    it := CreateInterpreter()
+    it.RegisterFunc("fmt", func(a int, b float64) (string, error) { return "", errors.New("boom") })
+    it.RegisterFunc("byte", func(a uint8) {})
+    it.RegisterFunc("long", func(a int64) {})
+    it.RegisterFunc("big", func() uint64 { return 1 << 63 })
+    it.RegisterFunc("join", func(v []string) {})
+    it.RegisterFunc("name", func(v String) {})
+    it.RegisterFunc("fail", func() error { return MakeError(ErrUser, "failed") })
+    for src, exp := range map[string]string {
+        `(fmt 1 2)`           : `fmt: boom`,
+        `(fmt 1)`             : `fmt: proc takes exact 2 arguments`,
+        `(fmt "1" 2)`         : `fmt: object is not an integer: "1"`,
+        `(fmt 1 2+3i)`        : `fmt: object is not a real number: 2+3i`,
+        `(byte 256)`          : `byte: integer is out of range for uint8: 256`,
+        `(byte -1)`           : `byte: integer is out of range for uint8: -1`,
+        `(byte (* 2 (big)))`  : `byte: integer is out of range for uint8: 18446744073709551616`,
+        `(long (* 2 (big)))`  : `long: integer is out of range for int64: 18446744073709551616`,
+        `(join '("a" . "b"))` : `join: object is not a proper list: ("a" . "b")`,
+        `(join '("a" 1))`     : `join: object is not a string: 1`,
+        `(join 1)`            : `join: object is not a list: 1`,
+        `(name '())`          : `name: object is not of type lisp.String: ()`,
+        `(fail)`              : `failed`,
+    } {
+        _, err := it.Eval(src)
+        require.EqualError(t, err, exp, src)
+    }
+    require.Panics(t, func() { BindFunc("x", 1) })
+    require.Panics(t, func() { BindFunc("x", func(map[string]int) {}) })
+    require.Panics(t, func() { BindFunc("x", func() (int, int) { return 0, 0 }) })
+}
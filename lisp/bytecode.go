@@ -0,0 +1,577 @@
+package lisp
+
+import (
+    `fmt`
+    `io`
+    `math`
+    `math/big`
+    `os`
+)
+
+const (
+    BytecodeExt     = ".slc"
+    BytecodeMagic   = "\x7fSLC"
+    BytecodeVersion = 2
+)
+
+const (
+    _T_nil uint8 = iota
+    _T_int
+    _T_bool
+    _T_char
+    _T_atom
+    _T_float
+    _T_string
+    _T_complex
+    _T_bigint
+    _T_rational
+    _T_list
+    _T_vector
+    _T_intrinsic
+    _T_unspecified
+)
+
+/** Bytecode Encoder **/
+
+type _Encoder struct {
+    buf   []byte
+    vals  []Value
+    procs []*Proc
+    vidx  map[interface{}]int
+    pidx  map[*Proc]int
+}
+
+func EncodeBytecode(progs []Program) []byte {
+    enc := &_Encoder {
+        vidx: make(map[interface{}]int),
+        pidx: make(map[*Proc]int),
+    }
+
+    /* every top-level program is encoded as a proc */
+    mains := make([]int, len(progs))
+    for i, p := range progs {
+        mains[i] = enc.proc(&Proc { Name: "#[main]", Code: p })
+    }
+
+    /* file header */
+    enc.buf = append(enc.buf, BytecodeMagic...)
+    enc.buf = append(enc.buf, BytecodeVersion & 0xff, BytecodeVersion >> 8)
+
+    /* constant pool */
+    enc.uvarint(uint64(len(enc.vals)))
+    for _, v := range enc.vals {
+        enc.value(v)
+    }
+
+    /* proc table */
+    enc.uvarint(uint64(len(enc.procs)))
+    for _, p := range enc.procs {
+        enc.procBody(p)
+    }
+
+    /* top-level programs */
+    enc.uvarint(uint64(len(mains)))
+    for _, i := range mains {
+        enc.uvarint(uint64(i))
+    }
+
+    /* all done */
+    return enc.buf
+}
+
+func (self *_Encoder) uvarint(v uint64) {
+    for v >= 0x80 {
+        self.buf = append(self.buf, byte(v) | 0x80)
+        v >>= 7
+    }
+    self.buf = append(self.buf, byte(v))
+}
+
+func (self *_Encoder) varint(v int64) {
+    self.uvarint(uint64(v << 1) ^ uint64(v >> 63))
+}
+
+func (self *_Encoder) str(v string) {
+    self.uvarint(uint64(len(v)))
+    self.buf = append(self.buf, v...)
+}
+
+func (self *_Encoder) float(v float64) {
+    bits := math.Float64bits(v)
+    for i := 0; i < 8; i++ {
+        self.buf = append(self.buf, byte(bits >> (i * 8)))
+    }
+}
+
+type (
+    _FloatKey   uint64
+    _ComplexKey [2]uint64
+)
+
+func constkey(v Value) interface{} {
+    switch vv := v.(type) {
+        case *List   : if vv == nil { return nil }
+        case Float   : return _FloatKey(math.Float64bits(float64(vv)))
+        case Complex : return _ComplexKey { math.Float64bits(real(vv)), math.Float64bits(imag(vv)) }
+    }
+    return v
+}
+
+func (self *_Encoder) constant(v Value) int {
+    key := constkey(v)
+
+    /* constants are shared within the pool, NaNs are compared by bits */
+    if i, ok := self.vidx[key]; ok {
+        return i
+    } else {
+        self.vidx[key] = len(self.vals)
+        self.vals = append(self.vals, v)
+        return len(self.vals) - 1
+    }
+}
+
+func (self *_Encoder) proc(p *Proc) int {
+    if i, ok := self.pidx[p]; ok {
+        return i
+    }
+
+    /* allocate the proc index first, since procs might be recursive */
+    idx := len(self.procs)
+    self.pidx[p] = idx
+    self.procs = append(self.procs, p)
+
+    /* collect all the names */
+    self.constant(Atom(p.Name))
+    self.constant(Atom(p.Rest))
+
+    /* argument names */
+    for _, v := range p.Args {
+        self.constant(Atom(v))
+    }
+
+    /* collect all the constants and nested procs */
+    for _, iv := range p.Code {
+        switch iv.Op() {
+            case OP_ldconst : self.constRefs(iv)
+            case OP_ldproc  : self.proc(iv.Fn())
+            default         : if iv.hasName() { self.constant(Atom(iv.Sv())) }
+        }
+    }
+
+    /* all done */
+    return idx
+}
+
+func (self *_Encoder) procBody(p *Proc) {
+    self.uvarint(uint64(self.constant(Atom(p.Name))))
+    self.uvarint(uint64(len(p.Args)))
+
+    /* argument names */
+    for _, v := range p.Args {
+        self.uvarint(uint64(self.constant(Atom(v))))
+    }
+
+    /* rest argument name, slot count and the instructions */
+    self.uvarint(uint64(self.constant(Atom(p.Rest))))
+    self.uvarint(uint64(p.Slots))
+    self.uvarint(uint64(len(p.Code)))
+
+    /* encode every instruction, operands are resolved into indexes */
+    for _, iv := range p.Code {
+        self.uvarint(uint64(iv.u0))
+        switch iv.Op() {
+            case OP_ldconst : self.constOperand(iv)
+            case OP_ldproc  : self.uvarint(uint64(self.pidx[iv.Fn()]))
+            default         : self.operand(iv)
+        }
+    }
+}
+
+func (self *_Encoder) constRefs(iv Instr) {
+    if self.constant(foldedValue(iv)); !isConstant(iv) {
+        self.proc(iv.Rv().(*_Folded).fn)
+    }
+}
+
+func (self *_Encoder) constOperand(iv Instr) {
+    self.uvarint(uint64(self.constant(foldedValue(iv))))
+    self.uvarint(uint64(iv.Iv()))
+
+    /* folded constants also carry the original instructions */
+    if !isConstant(iv) {
+        self.uvarint(uint64(self.pidx[iv.Rv().(*_Folded).fn]))
+    }
+}
+
+func (self *_Encoder) operand(iv Instr) {
+    if iv.hasName() {
+        self.uvarint(uint64(self.constant(Atom(iv.Sv()))))
+    } else {
+        self.uvarint(uint64(iv.Iv()))
+    }
+}
+
+func (self *_Encoder) value(v Value) {
+    switch vv := v.(type) {
+        default: {
+            panic(MakeError(ErrType, "slc: value cannot be serialized", v))
+        }
+
+        /* simple values */
+        case nil         : self.buf = append(self.buf, _T_nil)
+        case Int         : self.buf = append(self.buf, _T_int); self.varint(int64(vv))
+        case Char        : self.buf = append(self.buf, _T_char); self.uvarint(uint64(vv))
+        case Atom        : self.buf = append(self.buf, _T_atom); self.str(string(vv))
+        case Float       : self.buf = append(self.buf, _T_float); self.float(float64(vv))
+        case String      : self.buf = append(self.buf, _T_string); self.str(string(vv))
+        case *BigInt     : self.buf = append(self.buf, _T_bigint); self.str(vv.Int().String())
+        case *Rational   : self.buf = append(self.buf, _T_rational); self.str(vv.Rat().String())
+        case *Intrinsic  : self.buf = append(self.buf, _T_intrinsic); self.str(vv.Name)
+        case Unspecified : self.buf = append(self.buf, _T_unspecified)
+
+        /* boolean values */
+        case Bool: {
+            if self.buf = append(self.buf, _T_bool); vv {
+                self.buf = append(self.buf, 1)
+            } else {
+                self.buf = append(self.buf, 0)
+            }
+        }
+
+        /* complex values are encoded as two floats */
+        case Complex: {
+            self.buf = append(self.buf, _T_complex)
+            self.float(real(complex128(vv)))
+            self.float(imag(complex128(vv)))
+        }
+
+        /* lists are encoded as elements with the tail */
+        case *List: {
+            elem, tail := splitList(vv)
+            self.buf = append(self.buf, _T_list)
+            self.uvarint(uint64(len(elem)))
+            for _, x := range elem { self.value(x) }
+            self.value(tail)
+        }
+
+        /* vectors are encoded as elements */
+        case *Vector: {
+            self.buf = append(self.buf, _T_vector)
+            self.uvarint(uint64(len(vv.Elems)))
+            for _, x := range vv.Elems { self.value(x) }
+        }
+    }
+}
+
+/** Bytecode Decoder **/
+
+type _Decoder struct {
+    buf   []byte
+    pos   int
+    vals  []Value
+    procs []*Proc
+}
+
+func DecodeBytecode(buf []byte) (ret []Program, err error) {
+    err = CatchError(func() {
+        dec := &_Decoder { buf: buf }
+        ret = dec.decode()
+    })
+    return
+}
+
+func (self *_Decoder) error(msg string) *LispError {
+    return MakeError(ErrIO, fmt.Sprintf("slc: %s at offset %d", msg, self.pos))
+}
+
+func (self *_Decoder) decode() []Program {
+    if len(self.buf) < len(BytecodeMagic) + 2 || string(self.buf[:len(BytecodeMagic)]) != BytecodeMagic {
+        panic(self.error("invalid bytecode file"))
+    }
+
+    /* check for version */
+    self.pos = len(BytecodeMagic) + 2
+    ver := int(self.buf[self.pos - 2]) | int(self.buf[self.pos - 1]) << 8
+
+    /* only the current version is supported */
+    if ver != BytecodeVersion {
+        panic(self.error(fmt.Sprintf("unsupported bytecode version %d", ver)))
+    }
+
+    /* constant pool */
+    self.vals = make([]Value, self.count())
+    for i := range self.vals {
+        self.vals[i] = self.value()
+    }
+
+    /* allocate all the procs first, since they may reference each other */
+    self.procs = make([]*Proc, self.count())
+    for i := range self.procs {
+        self.procs[i] = new(Proc)
+    }
+
+    /* decode every proc */
+    for _, p := range self.procs {
+        self.procBody(p)
+    }
+
+    /* top-level programs */
+    ret := make([]Program, self.count())
+    for i := range ret {
+        ret[i] = self.procs[self.index(len(self.procs))].Code
+    }
+
+    /* check for trailing data */
+    if self.pos != len(self.buf) {
+        panic(self.error("unexpected trailing data"))
+    }
+
+    /* verify all the programs before running */
+    for _, p := range ret {
+        if err := Verify(p); err != nil {
+            panic(err)
+        }
+    }
+
+    /* all done */
+    return ret
+}
+
+func (self *_Decoder) byte() byte {
+    if self.pos >= len(self.buf) {
+        panic(self.error("unexpected end of file"))
+    } else {
+        self.pos++
+        return self.buf[self.pos - 1]
+    }
+}
+
+func (self *_Decoder) uvarint() (ret uint64) {
+    for s := uint(0); ; s += 7 {
+        if b := self.byte(); s >= 64 {
+            panic(self.error("varint overflow"))
+        } else if ret |= uint64(b & 0x7f) << s; b < 0x80 {
+            return
+        }
+    }
+}
+
+func (self *_Decoder) varint() int64 {
+    v := self.uvarint()
+    return int64(v >> 1) ^ -int64(v & 1)
+}
+
+func (self *_Decoder) count() int {
+    if v := self.uvarint(); v > uint64(len(self.buf)) {
+        panic(self.error("invalid item count"))
+    } else {
+        return int(v)
+    }
+}
+
+func (self *_Decoder) index(limit int) int {
+    if v := self.uvarint(); v >= uint64(limit) {
+        panic(self.error("index out of range"))
+    } else {
+        return int(v)
+    }
+}
+
+func (self *_Decoder) str() string {
+    if n := self.count(); self.pos + n > len(self.buf) {
+        panic(self.error("unexpected end of file"))
+    } else {
+        self.pos += n
+        return string(self.buf[self.pos - n:self.pos])
+    }
+}
+
+func (self *_Decoder) float() float64 {
+    var bits uint64
+    for i := 0; i < 8; i++ { bits |= uint64(self.byte()) << (i * 8) }
+    return math.Float64frombits(bits)
+}
+
+func (self *_Decoder) name() string {
+    if at, ok := self.vals[self.index(len(self.vals))].(Atom); !ok {
+        panic(self.error("constant is not a name"))
+    } else {
+        return string(at)
+    }
+}
+
+func (self *_Decoder) procBody(p *Proc) {
+    p.Name = self.name()
+    p.Args = make([]string, self.count())
+
+    /* argument names */
+    for i := range p.Args {
+        p.Args[i] = self.name()
+    }
+
+    /* rest argument name, slot count and the instructions */
+    p.Rest = self.name()
+    p.Slots = self.count()
+    p.Code = make(Program, self.count())
+
+    /* decode every instruction */
+    for i := range p.Code {
+        u0 := self.uvarint()
+        iv := Instr { u0: uint32(u0) }
+
+        /* check for instruction encoding */
+        if u0 > math.MaxUint32 {
+            panic(self.error("invalid instruction"))
+        }
+
+        /* decode the operand */
+        switch op := iv.Op(); {
+            case op == OP_ldconst : iv = self.constant()
+            case op == OP_ldproc  : iv = mkins(op, 0, "", nil, self.procs[self.index(len(self.procs))])
+            case iv.hasName()     : iv = mkins(op, 0, self.name(), nil, nil)
+            default               : iv = mkins(op, uint32(self.uvarint()), "", nil, nil)
+        }
+
+        /* preserve all the other bits */
+        iv.u0 = uint32(u0)
+        p.Code[i] = iv
+    }
+}
+
+func (self *_Decoder) constant() Instr {
+    val := self.vals[self.index(len(self.vals))]
+    mask := self.uvarint()
+
+    /* check for the guard mask */
+    if mask > math.MaxUint32 {
+        panic(self.error("invalid guard mask"))
+    }
+
+    /* folded constants also carry the original instructions */
+    if mask != 0 {
+        val = &_Folded { val: val, fn: self.procs[self.index(len(self.procs))] }
+    }
+
+    /* construct the instruction */
+    return mkins(OP_ldconst, uint32(mask), "", val, nil)
+}
+
+func (self *_Decoder) value() Value {
+    switch tag := self.byte(); tag {
+        default: {
+            panic(self.error(fmt.Sprintf("invalid value tag %d", tag)))
+        }
+
+        /* simple values */
+        case _T_nil         : return nil
+        case _T_unspecified : return Unspecified{}
+        case _T_int         : return Int(self.varint())
+        case _T_bool        : return Bool(self.byte() != 0)
+        case _T_char        : return Char(self.uvarint())
+        case _T_atom        : return Atom(self.str())
+        case _T_float       : return Float(self.float())
+        case _T_string      : return String(self.str())
+
+        /* complex values are encoded as two floats */
+        case _T_complex: {
+            re := self.float()
+            im := self.float()
+            return Complex(complex(re, im))
+        }
+
+        /* big integers */
+        case _T_bigint: {
+            if v, ok := new(big.Int).SetString(self.str(), 10); !ok {
+                panic(self.error("invalid big integer"))
+            } else {
+                return MakeInteger(v)
+            }
+        }
+
+        /* rational numbers */
+        case _T_rational: {
+            if v, ok := new(big.Rat).SetString(self.str()); !ok {
+                panic(self.error("invalid rational number"))
+            } else {
+                return MakeRational(v)
+            }
+        }
+
+        /* intrinsics are referenced by names */
+        case _T_intrinsic: {
+            if fn, ok := intrinsicsTab[self.str()]; !ok {
+                panic(self.error("unknown intrinsic"))
+            } else {
+                return fn
+            }
+        }
+
+        /* lists are encoded as elements with the tail */
+        case _T_list: {
+            elem := make([]Value, self.count())
+            for i := range elem { elem[i] = self.value() }
+            return rebuildList(elem, self.value())
+        }
+
+        /* vectors are encoded as elements */
+        case _T_vector: {
+            elem := make([]Value, self.count())
+            for i := range elem { elem[i] = self.value() }
+            return MakeVector(elem)
+        }
+    }
+}
+
+/** Bytecode Files **/
+
+func ReadBytecodeFile(fname string) ([]Program, error) {
+    var nb int
+    var err error
+    var rfp *os.File
+
+    /* open the file */
+    if rfp, err = os.OpenFile(fname, os.O_RDONLY, 0); err != nil {
+        return nil, MakeError(ErrIO, fmt.Sprintf("io: unable to open %s: %s", fname, err))
+    }
+
+    /* read the whole file */
+    buf := make([]byte, 0, 4096)
+    defer rfp.Close()
+
+    /* read until EOF */
+    for {
+        if len(buf) == cap(buf) {
+            buf = append(buf, 0)[:len(buf)]
+        }
+
+        /* read the next chunk */
+        if nb, err = rfp.Read(buf[len(buf):cap(buf)]); err == io.EOF {
+            return DecodeBytecode(buf)
+        } else if buf = buf[:len(buf) + nb]; err != nil {
+            return nil, MakeError(ErrIO, fmt.Sprintf("io: unable to read %s: %s", fname, err))
+        }
+    }
+}
+
+func WriteBytecodeFile(fname string, progs []Program) error {
+    var err error
+    var wfp *os.File
+
+    /* create the file */
+    if wfp, err = os.OpenFile(fname, os.O_WRONLY | os.O_CREATE | os.O_TRUNC, 0644); err != nil {
+        return MakeError(ErrIO, fmt.Sprintf("io: unable to create %s: %s", fname, err))
+    }
+
+    /* write the serialized bytecode */
+    if _, err = wfp.Write(EncodeBytecode(progs)); err != nil {
+        wfp.Close()
+        return MakeError(ErrIO, fmt.Sprintf("io: unable to write %s: %s", fname, err))
+    }
+
+    /* flush and close the file */
+    if err = wfp.Close(); err != nil {
+        return MakeError(ErrIO, fmt.Sprintf("io: unable to write %s: %s", fname, err))
+    } else {
+        return nil
+    }
+}
@@ -0,0 +1,71 @@
+package lisp
+
+import (
+    `math/big`
+    `strconv`
+    `strings`
+    `testing`
+
+    `github.com/stretchr/testify/require`
+)
+
+func roundtrip(t *testing.T, src string) []Program {
+    ret, err := CreateInterpreter().Compile("<test>", strings.NewReader(src))
+    require.NoError(t, err)
+    out, err := DecodeBytecode(EncodeBytecode(ret))
+    require.NoError(t, err)
+    require.Equal(t, len(ret), len(out))
+    for i := range ret {
+        require.Equal(t, ret[i].String(), out[i].String())
+    }
+    return out
+}
+
+func evalbytecode(t *testing.T, src string) (ret Value) {
+    it := CreateInterpreter()
+    for _, p := range roundtrip(t, src) {
+        ret = it.evaluate(p)
+    }
+    return
+}
+
+func TestBytecode_RoundTrip(t *testing.T) {
+    require.Equal(t, "3628800", AsString(evalbytecode(t, "(define (fac n) (if (= n 0) 1 (* n (fac (- n 1))))) (fac 10)")))
+    require.Equal(t, "(a 5 1 2 3 b)", AsString(evalbytecode(t, "(define x 5) (define r (list 1 2 3)) `(a ,x ,@r b)")))
+    require.Equal(t, "#(1 7 2 3)", AsString(evalbytecode(t, "(define x 7) `#(1 ,x ,@(list 2 3))")))
+    require.Equal(t, "(1 2.5 \"s\" #\\c (x . y) 1/3 100000000000000000000 1+2i ())", AsString(evalbytecode(t, "'(1 2.5 \"s\" #\\c (x . y) 1/3 100000000000000000000 1+2i ())")))
+    require.Equal(t, "(2 1)", AsString(evalbytecode(t, "(define-syntax swap! (syntax-rules () ((_ a b) (let ((tmp a)) (set! a b) (set! b tmp))))) (define p 1) (define q 2) (swap! p q) (list p q)")))
+    require.Equal(t, "(1 (2 3))", AsString(evalbytecode(t, "(define (f x . more) (list x more)) (f 1 2 3)")))
+    require.Equal(t, Unspecified{}, evalbytecode(t, "(define x 1) (if #f #f)"))
+    require.Equal(t, "(10 3)", AsString(evalbytecode(t, "(define (f) (+ (* 2 3) 4)) (define a (f)) (set! * -) (list a (f))")))
+}
+
+func TestBytecode_Invalid(t *testing.T) {
+    buf := EncodeBytecode(roundtrip(t, "(display 1)"))
+    _, err := DecodeBytecode([]byte("(display 1)"))
+    require.EqualError(t, err, "slc: invalid bytecode file at offset 0")
+    _, err = DecodeBytecode(append(append([]byte(nil), buf[:4]...), 99, 0))
+    require.EqualError(t, err, "slc: unsupported bytecode version 99 at offset 6")
+    _, err = DecodeBytecode(buf[:len(buf) - 1])
+    require.EqualError(t, err, "slc: unexpected end of file at offset " + strconv.Itoa(len(buf) - 1))
+    _, err = DecodeBytecode(append(append([]byte(nil), buf...), 0))
+    require.Error(t, err)
+    fn := &Proc { Name: "f" }
+    fn.Code.fnp(OP_ldproc, fn)
+    fn.Code.add(OP_return)
+    var p Program
+    p.fnp(OP_ldproc, fn)
+    p.add(OP_return)
+    _, err = DecodeBytecode(EncodeBytecode([]Program { p }))
+    require.Error(t, err)
+    require.Contains(t, err.Error(), "recursive proc reference")
+}
+
+func TestBytecode_Normalize(t *testing.T) {
+    var p Program
+    p.val(OP_ldconst, (*BigInt)(big.NewInt(42)))
+    p.add(OP_return)
+    out, err := DecodeBytecode(EncodeBytecode([]Program { p }))
+    require.NoError(t, err)
+    require.Equal(t, Int(42), out[0][0].Rv())
+}
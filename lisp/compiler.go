@@ -0,0 +1,1091 @@
+package lisp
+
+import (
+    `fmt`
+    `strconv`
+    `strings`
+    `unsafe`
+)
+
+type (
+    OpCode   uint8
+    LetKind  uint8
+    RelKind  uint8
+    Program  []Instr
+)
+
+const (
+    Lambda        = "λ"
+    MaxSourceLine = 0xffffff
+)
+
+const (
+    _ OpCode = iota
+    OP_ldconst          // ldconst      <val>       : Push value <val> onto stack.
+    OP_ldproc           // ldproc       <proc>      : Push proc <proc> onto stack.
+    OP_ldvar            // ldvar        <name>      : Push the content of variable <name> onto stack.
+    OP_define           // define       <name>      : Define a variable <name> with content at the stack top.
+    OP_set              // set          <name>      : Set the variable <name> to content at the stack top.
+    OP_ldlocal          // ldlocal      <addr>      : Push the content of local variable at <addr> onto stack.
+    OP_stlocal          // stlocal      <addr>      : Store the stack top into local variable at <addr>.
+    OP_car              // car                      : Get the first half of a pair.
+    OP_cdr              // cdr                      : Get the second half of a pair.
+    OP_cons             // cons                     : Construct a new pair from stack top.
+    OP_drop             // drop                     : Drop one value from stack.
+    OP_goto             // goto         <pc>        : Goto <pc> unconditionally.
+    OP_if_false         // if_false     <pc>        : Pop a value from the stack, if it is #f, goto <pc>.
+    OP_assert_true      // assert_true  <pc>        : Discard the stack top if is #t, otherwise goto <pc>.
+    OP_assert_false     // assert_false <pc>        : Discard the stack top if is #f, otherwise goto <pc>.
+    OP_apply            // apply        <argc>      : Apply procedure on stack with <argc> arguments.
+    OP_tailcall         // tailcall     <argc>      : Like OP_apply, but with tail-call optimizations.
+    OP_return           // return                   : Return from procedure.
+    OP_add              // add          <name>      : Add the top two values on stack, if <name> is not redefined.
+    OP_sub              // sub          <name>      : Subtract the top two values on stack, if <name> is not redefined.
+    OP_mul              // mul          <name>      : Multiply the top two values on stack, if <name> is not redefined.
+    OP_div              // div          <name>      : Divide the top two values on stack, if <name> is not redefined.
+    OP_eq               // eq           <name>      : Compare the top two values on stack for =, if <name> is not redefined.
+    OP_lt               // lt           <name>      : Compare the top two values on stack for <, if <name> is not redefined.
+    OP_gt               // gt           <name>      : Compare the top two values on stack for >, if <name> is not redefined.
+    OP_le               // le           <name>      : Compare the top two values on stack for <=, if <name> is not redefined.
+    OP_ge               // ge           <name>      : Compare the top two values on stack for >=, if <name> is not redefined.
+)
+
+const (
+    Let LetKind = iota
+    LetRec
+    LetStar
+)
+
+const (
+    Conjunctive RelKind = iota
+    Disjunctive
+)
+
+type Compiler struct {
+    Spans      SourceMap
+    Global     *Environ
+    NoOptimize bool
+    env        *Environ
+    span       Span
+}
+
+type Instr struct {
+    u0 uint32
+    u1 uint32
+    p0 unsafe.Pointer
+    p1 unsafe.Pointer
+}
+
+func rvstr(v Value) string {
+    switch vv := v.(type) {
+        case Int      : return fmt.Sprintf("(int) %s", v)
+        case Float    : return fmt.Sprintf("(float) %s", v)
+        case *_Folded : return rvstr(vv.val)
+        default       : return AsString(v)
+    }
+}
+
+func (self Instr) Iv() uint32     { return self.u1 }
+func (self Instr) Op() OpCode     { return OpCode(self.u0) }
+func (self Instr) Line() int      { return int(self.u0 >> 8) }
+func (self Instr) Fn() *Proc      { return (*Proc)(self.p0) }
+func (self Instr) Rv() Value      { return mkval(self.p0, self.p1).pack() }
+func (self Instr) Sv() string     { return mkstr(self.p0, int(self.u1)).String() }
+func (self Instr) Av() (int, int) { return int(self.u1 >> 16), int(self.u1 & 0xffff) }
+
+func (self Instr) String() string {
+    switch self.Op() {
+        case OP_ldconst      : return fmt.Sprintf("ldconst     %s", rvstr(self.Rv()))
+        case OP_ldproc       : return fmt.Sprintf("ldproc      %s", self.Fn().String())
+        case OP_ldvar        : return fmt.Sprintf("ldvar       %s", self.Sv())
+        case OP_define       : return fmt.Sprintf("define      %s", self.Sv())
+        case OP_set          : return fmt.Sprintf("set         %s", self.Sv())
+        case OP_ldlocal      : return fmt.Sprintf("ldlocal     %s", self.addr())
+        case OP_stlocal      : return fmt.Sprintf("stlocal     %s", self.addr())
+        case OP_car          : return "car"
+        case OP_cdr          : return "cdr"
+        case OP_cons         : return "cons"
+        case OP_drop         : return "drop"
+        case OP_goto         : return fmt.Sprintf("goto        @%d", self.Iv())
+        case OP_if_false     : return fmt.Sprintf("if.#f       @%d", self.Iv())
+        case OP_assert_true  : return fmt.Sprintf("assert.#t   @%d", self.Iv())
+        case OP_assert_false : return fmt.Sprintf("assert.#f   @%d", self.Iv())
+        case OP_apply        : return fmt.Sprintf("apply       #%d", self.Iv())
+        case OP_tailcall     : return fmt.Sprintf("tailcall    #%d", self.Iv())
+        case OP_return       : return "return"
+        case OP_add          : return "add"
+        case OP_sub          : return "sub"
+        case OP_mul          : return "mul"
+        case OP_div          : return "div"
+        case OP_eq           : return "eq"
+        case OP_lt           : return "lt"
+        case OP_gt           : return "gt"
+        case OP_le           : return "le"
+        case OP_ge           : return "ge"
+        default              : return fmt.Sprintf("OpCode(%d)", self.Op())
+    }
+}
+
+func (self Instr) addr() string {
+    depth, index := self.Av()
+    return fmt.Sprintf("[%d, %d]", depth, index)
+}
+
+func (self *Instr) setOp(op OpCode) {
+    self.u0 = self.u0 &^ 0xff | uint32(op)
+}
+
+func (self *Instr) setLine(line int) {
+    if line > 0 && line <= MaxSourceLine {
+        self.u0 = uint32(self.Op()) | uint32(line) << 8
+    }
+}
+
+func (self Instr) withLine(line int) Instr {
+    self.setLine(line)
+    return self
+}
+
+func (self Instr) hasName() bool {
+    switch self.Op() {
+        case OP_ldvar, OP_define, OP_set                                     : return true
+        case OP_add, OP_sub, OP_mul, OP_div, OP_eq, OP_lt, OP_gt, OP_le, OP_ge : return true
+        default                                                              : return false
+    }
+}
+
+func mku1(iv uint32, sv string) uint32 {
+    if sv == "" {
+        return iv
+    } else if iv == 0 {
+        return uint32(len(sv))
+    } else {
+        panic("fatal: encoding confliction between iv and sv")
+    }
+}
+
+func mkp0(sv string, rv Value, fn *Proc) unsafe.Pointer {
+    if sv == "" && rv == nil && fn == nil {
+        return nil
+    } else if sv != "" && rv == nil && fn == nil {
+        return straddr(sv)
+    } else if sv == "" && rv != nil && fn == nil {
+        return valitab(rv)
+    } else if sv == "" && rv == nil && fn != nil {
+        return unsafe.Pointer(fn)
+    } else {
+        panic("fatal: encoding confliction between sv, rv and fn")
+    }
+}
+
+func mkins(op OpCode, iv uint32, sv string, rv Value, fn *Proc) Instr {
+    return Instr {
+        u0: uint32(op),
+        u1: mku1(iv, sv),
+        p0: mkp0(sv, rv, fn),
+        p1: valaddr(rv),
+    }
+}
+
+func (self Program) pc() int   { return len(self) }
+func (self Program) pin(p int) { self[p].u1 = uint32(self.pc()) }
+
+func (self *Program) mark(p int, line int) {
+    for i := p; i < len(*self); i++ {
+        if (*self)[i].Line() == 0 {
+            (*self)[i].setLine(line)
+        }
+    }
+}
+
+func (self *Program) add(op OpCode)             { *self = append(*self, mkins(op, 0, "", nil, nil)) }
+func (self *Program) jmp(op OpCode, val int)    { *self = append(*self, mkins(op, uint32(val), "", nil, nil)) }
+func (self *Program) fnp(op OpCode, val *Proc)  { *self = append(*self, mkins(op, 0, "", nil, val)) }
+func (self *Program) val(op OpCode, val Value)  { *self = append(*self, mkins(op, 0, "", val, nil)) }
+func (self *Program) i32(op OpCode, val uint32) { *self = append(*self, mkins(op, val, "", nil, nil)) }
+func (self *Program) str(op OpCode, val string) { *self = append(*self, mkins(op, 0, val, nil, nil)) }
+
+func (self Program) String() string {
+    return self.Disassemble(false)
+}
+
+func (self Program) Disassemble(lines bool) string {
+    return (&Proc { Name: "#[main]", Code: self }).Disassemble(lines)
+}
+
+func (self Program) Disasm(lines bool) (string, []*Proc) {
+    var idx int
+    var val Instr
+    var ret []*Proc
+    var buf []string
+
+    /* empty program */
+    if len(self) == 0 {
+        return "(empty program)", nil
+    }
+
+    /* count the maximum digits */
+    nb := len(self) - 1
+    nd := len(strconv.Itoa(nb))
+    fs := fmt.Sprintf("%%%dd :  %%s\n", nd)
+
+    /* source lines are aligned after the widest instruction */
+    nw := 0
+    ls := fmt.Sprintf("%%%dd :  %%-*s  ; line %%d\n", nd)
+
+    /* find the widest instruction */
+    for _, val = range self {
+        if n := len(val.String()); lines && n > nw {
+            nw = n
+        }
+    }
+
+    /* disassemble every instruction */
+    for idx, val = range self {
+        if lines && val.Line() != 0 {
+            buf = append(buf, fmt.Sprintf(ls, idx, nw, val, val.Line()))
+        } else {
+            buf = append(buf, fmt.Sprintf(fs, idx, val))
+        }
+
+        /* also disassemble the nested procs */
+        if val.Op() == OP_ldproc {
+            ret = append(ret, val.Fn())
+        }
+    }
+
+    /* pack into result */
+    return strings.Join(buf, ""), ret
+}
+
+func (self Compiler) Compile(src *List) (p Program) {
+    top := self.env == nil
+
+    /* top-level programs are compiled within the global environment */
+    if top {
+        self.env = self.Global
+    }
+
+    /* create a new global environment if not specified */
+    if self.env == nil {
+        self.env = CreateEnviron()
+    }
+
+    /* aliases created by macro expansions are dropped along with the top-level program */
+    if top {
+        defer self.env.Sweep()
+    }
+
+    /* compile the program */
+    self.compileList(&p, src)
+    p.add(OP_return)
+
+    /* optimize the program if not disabled */
+    if !self.NoOptimize {
+        p = Optimize(p)
+    }
+
+    /* tail-calls are optimized unconditionally */
+    OptimizeTailCall(p)
+
+    /* verify the entire program, including all the nested procs */
+    if top {
+        if err := Verify(p); err != nil {
+            panic(err)
+        }
+    }
+
+    /* all done */
+    return
+}
+
+func (self Compiler) error(msg string, irritants ...Value) *LispError {
+    return MakeError(ErrCompile, "compile: " + msg, irritants...).At(self.span)
+}
+
+func (self Compiler) errorAt(v Value, msg string, irritants ...Value) *LispError {
+    if sp, ok := self.Spans.Locate(v); ok {
+        self.span = sp
+    }
+    return self.error(msg, irritants...)
+}
+
+/** Sub-type Compiling **/
+
+func (self Compiler) compileUnspecified(p *Program) {
+    p.add(OP_drop)
+    p.val(OP_ldconst, Unspecified{})
+}
+
+func (self Compiler) compileSet(p *Program, v *List) {
+    var ok bool
+    var sn Atom
+    var vv *List
+
+    /* unpack the variable name and value */
+    if v == nil                     { panic(self.error("malformed set! construct", v)) }
+    if sn, ok = v.Car.(Atom) ; !ok { panic(self.error("malformed set! construct", v)) }
+    if vv, ok = v.Cdr.(*List); !ok { panic(self.error("malformed set! construct", v)) }
+    if vv.Cdr != nil               { panic(self.error("malformed set! construct", v)) }
+
+    /* emit the opcode */
+    self.compileValue(p, vv.Car)
+    self.compileStore(p, sn)
+    self.compileUnspecified(p)
+}
+
+func (self Compiler) compileList(p *Program, v *List) {
+    var ok bool
+    var at Atom
+    var vv *List
+
+    /* empty list evaluates to nil */
+    if v == nil {
+        p.val(OP_ldconst, nil)
+        return
+    }
+
+    /* update the source location, desugared forms inherit the location of the original one */
+    if sp, ok := self.Spans[v]; ok {
+        self.span = sp
+    }
+
+    /* instructions that are not attributed to any sub-forms belong to this form */
+    defer p.mark(p.pc(), self.span.Row)
+
+    /* (car v) is not an atom, apply the list immediately */
+    if at, ok = v.Car.(Atom); !ok {
+        p.i32(OP_apply, self.compileArgs(p, v, -1))
+        return
+    }
+
+    /* must be a proper list to be applicable */
+    if vv, ok = AsList(v.Cdr); !ok {
+        panic(self.error("improper list is not applicable", v))
+    }
+
+    /* expand macros, identifiers that are lexically bound are always applied */
+    if env, name, macro := self.env.Resolve(at); macro != nil {
+        self.compileValue(p, self.expandMacro(macro, v))
+        return
+    } else if env != nil {
+        p.i32(OP_apply, self.compileArgs(p, v, -1))
+        return
+    } else {
+        at = name
+    }
+
+    /* check for built-in atoms */
+    switch at {
+        case "or"               : self.compileShortCircuit(p, vv, Disjunctive)
+        case "and"              : self.compileShortCircuit(p, vv, Conjunctive)
+        case "car"              : self.compileArgs(p, vv, 1); p.add(OP_car)
+        case "cdr"              : self.compileArgs(p, vv, 1); p.add(OP_cdr)
+        case "cons"             : self.compileArgs(p, vv, 2); p.add(OP_cons)
+        case "set!"             : self.compileSet(p, vv)
+        case "begin"            : self.compileBlock(p, vv)
+        case "quote"            : self.compileQuote(p, vv)
+        case "quasiquote"       : self.compileQuasiquote(p, vv)
+        case "unquote"          : fallthrough
+        case "unquote-splicing" : panic(self.error(fmt.Sprintf("`%s` outside of `quasiquote`", at), v))
+        case "define"           : self.compileDefine(p, vv)
+        case "define-syntax"    : self.compileDefineSyntax(p, vv)
+        case "let-syntax"       : self.compileLetSyntax(p, vv, false)
+        case "letrec-syntax"    : self.compileLetSyntax(p, vv, true)
+        case Lambda             : fallthrough
+        case "lambda"           : self.compileLambda(p, vv, fmt.Sprintf("#[lambda-%d]", self.env.ids.Next()))
+        case "if"               : self.compileCondition(p, vv)
+        case "do"               : self.compileList(p, self.desugarDo(vv))
+        case "let"              : self.compileList(p, self.desugarLet(vv, Let))
+        case "let*"             : self.compileList(p, self.desugarLet(vv, LetStar))
+        case "letrec"           : self.compileList(p, self.desugarLet(vv, LetRec))
+        case "+"                : self.compilePrimitive(p, v, at)
+        case "-"                : self.compilePrimitive(p, v, at)
+        case "*"                : self.compilePrimitive(p, v, at)
+        case "/"                : self.compilePrimitive(p, v, at)
+        case "="                : self.compilePrimitive(p, v, at)
+        case "<"                : self.compilePrimitive(p, v, at)
+        case ">"                : self.compilePrimitive(p, v, at)
+        case "<="               : self.compilePrimitive(p, v, at)
+        case ">="               : self.compilePrimitive(p, v, at)
+        default                 : p.i32(OP_apply, self.compileArgs(p, v, -1))
+    }
+}
+
+func (self Compiler) compilePrimitive(p *Program, v *List, name Atom) {
+    var ok bool
+    var vv *List
+
+    /* only binary operations have dedicated opcodes */
+    if vv, ok = v.Cdr.(*List); ok && vv != nil {
+        if vv, ok = vv.Cdr.(*List); ok && vv != nil && vv.Cdr == nil {
+            self.compileArgs(p, v.Cdr.(*List), 2)
+            p.str(_PrimitiveOps[name], string(name))
+            return
+        }
+    }
+
+    /* otherwise apply the proc as usual */
+    p.i32(OP_apply, self.compileArgs(p, v, -1))
+}
+
+func (self Compiler) compileArgs(p *Program, v *List, n int) uint32 {
+    var nb int
+    var ok bool
+    var vv *List
+
+    /* scan every element */
+    for s := v; s != nil; s, nb = vv, nb + 1 {
+        if vv, ok = AsList(s.Cdr); !ok {
+            panic(self.error("improper list is not applicable", v))
+        } else {
+            self.compileValue(p, s.Car)
+        }
+    }
+
+    /* apply the tuple */
+    if n < 0 || n == nb {
+        return uint32(nb)
+    } else {
+        panic(self.error(fmt.Sprintf("expect %d arguments, got %d.", n, nb)))
+    }
+}
+
+func (self Compiler) compileBlock(p *Program, v *List) {
+    for v != nil {
+        ok := false
+        self.compileValue(p, v.Car)
+
+        /* drop the value if not the last one */
+        if v.Cdr != nil {
+            p.add(OP_drop)
+        }
+
+        /* check for proper list */
+        if v, ok = AsList(v.Cdr); !ok {
+            panic(self.error("block must be a proper list", v))
+        }
+    }
+}
+
+func (self Compiler) compileValue(p *Program, v Value) {
+    if v.IsIdentity() {
+        p.val(OP_ldconst, self.env.Strip(v))
+    } else if at, ok := v.(Atom); ok {
+        self.compileLoad(p, at)
+    } else if sl, ok := AsList(v); ok {
+        self.compileList(p, sl)
+    } else {
+        panic("fatal: compile: invalid value type")
+    }
+}
+
+func (self Compiler) compileLoad(p *Program, v Atom) {
+    if env, name, macro := self.env.Resolve(v); macro != nil {
+        panic(self.error(fmt.Sprintf("syntax keyword `%s` cannot be used as a variable", macro.Name)))
+    } else if env == nil || env.IsGlobal() {
+        p.str(OP_ldvar, string(name))
+    } else {
+        p.i32(OP_ldlocal, self.address(env, name))
+    }
+}
+
+func (self Compiler) compileStore(p *Program, v Atom) {
+    if env, name, macro := self.env.Resolve(v); macro != nil {
+        panic(self.error(fmt.Sprintf("syntax keyword `%s` cannot be used as a variable", macro.Name)))
+    } else if env == nil || env.IsGlobal() {
+        p.str(OP_set, string(name))
+    } else {
+        p.i32(OP_stlocal, self.address(env, name))
+    }
+}
+
+func (self Compiler) compileDefineStore(p *Program, env *Environ, name Atom) {
+    if env.IsGlobal() {
+        p.str(OP_define, string(name))
+    } else {
+        p.i32(OP_stlocal, self.address(env, name))
+    }
+}
+
+func (self Compiler) compileQuote(p *Program, v *List) {
+    if v != nil && v.Cdr == nil {
+        p.val(OP_ldconst, self.env.Strip(v.Car))
+    } else {
+        panic(self.error("`quote` takes exact 1 argument", v))
+    }
+}
+
+func (self Compiler) compileQuasiquote(p *Program, v *List) {
+    if v != nil && v.Cdr == nil {
+        self.compileTemplate(p, v.Car, 1)
+    } else {
+        panic(self.error("`quasiquote` takes exact 1 argument", v))
+    }
+}
+
+func (self Compiler) compileTemplate(p *Program, v Value, depth int) {
+    var ok bool
+    var at Atom
+    var vv *List
+    var sv *List
+
+    /* templates without any unquoting are just constants */
+    if !self.hasUnquote(v) {
+        p.val(OP_ldconst, self.env.Strip(v))
+        return
+    }
+
+    /* vector templates are constructed from list templates */
+    if vec, ok := v.(*Vector); ok {
+        p.val(OP_ldconst, intrinsicsTab["list->vector"])
+        self.compileTemplate(p, MakeList(vec.Elems...), depth)
+        p.i32(OP_apply, 2)
+        return
+    }
+
+    /* must be a non-empty list to contain any unquoting */
+    if vv, ok = v.(*List); !ok {
+        panic("fatal: compile: invalid template type")
+    }
+
+    /* check for nested quasi-quoting forms */
+    if at, sv, ok = self.quasiForm(vv); ok {
+        switch {
+            case at == "quasiquote" : self.compileNestedTemplate(p, at, sv.Car, depth + 1)
+            case depth != 1         : self.compileNestedTemplate(p, at, sv.Car, depth - 1)
+            case at == "unquote"    : self.compileValue(p, sv.Car)
+            default                 : panic(self.error("`unquote-splicing` is not in a list context", vv))
+        }
+        return
+    }
+
+    /* splice the list into the remaining part if needed */
+    if at, sv, ok = self.quasiForm(vv.Car); ok && at == "unquote-splicing" && depth == 1 {
+        self.compileSplicing(p, sv.Car, vv.Cdr)
+        return
+    }
+
+    /* construct a new pair */
+    self.compileTemplate(p, vv.Car, depth)
+    self.compileTemplate(p, vv.Cdr, depth)
+    p.add(OP_cons)
+}
+
+func (self Compiler) compileSplicing(p *Program, v Value, rem Value) {
+    if rem == nil {
+        self.compileValue(p, v)
+    } else {
+        p.val(OP_ldconst, intrinsicsTab["append"])
+        self.compileValue(p, v)
+        self.compileTemplate(p, rem, 1)
+        p.i32(OP_apply, 3)
+    }
+}
+
+func (self Compiler) compileNestedTemplate(p *Program, name Atom, v Value, depth int) {
+    p.val(OP_ldconst, name)
+    self.compileTemplate(p, v, depth)
+    p.val(OP_ldconst, nil)
+    p.add(OP_cons)
+    p.add(OP_cons)
+}
+
+func (self Compiler) compileDefine(p *Program, v *List) {
+    var name Atom
+    var decl *List
+
+    /* list header */
+    pp := v
+    ok := false
+
+    /* check for define expression */
+    if v == nil                                     { panic(self.error("malformed define construct", v)) }
+    if pp, ok = v.Cdr.(*List); !ok                  { panic(self.error("malformed define construct", v)) }
+    if name, ok = v.Car.(Atom); ok && pp.Cdr != nil { panic(self.error("malformed define construct", v)) }
+
+    /* defining values */
+    if ok {
+        env, name := self.defineIdent(name)
+        self.compileValue(p, pp.Car)
+        self.compileDefineStore(p, env, name)
+        self.compileUnspecified(p)
+        return
+    }
+
+    /* defining functions, the first part must be a list */
+    if decl, ok = v.Car.(*List)  ; !ok { panic(self.error("malformed define construct", v)) }
+    if name, ok = decl.Car.(Atom); !ok { panic(self.error("malformed define construct", v)) }
+
+    /* construct a lambda expression, and store to the variable */
+    env, name := self.defineIdent(name)
+    self.compileLambda(p, MakePair(decl.Cdr, pp), string(name))
+    self.compileDefineStore(p, env, name)
+    self.compileUnspecified(p)
+}
+
+func (self Compiler) compileLambda(p *Program, v *List, name string) {
+    var ok bool
+    var rest string
+    var decl *List
+    var proc *List
+    var args []string
+
+    /* extract the lambda body */
+    if v == nil                      { panic(self.error("malformed proc construct", v)) }
+    if proc, ok = AsList(v.Cdr); !ok { panic(self.error("malformed proc construct", v)) }
+
+    /* procedure body has it's own lexical environment */
+    env := self.env.Derive()
+    self.env = env
+
+    /* parse the argument names, an identifier at the tail position collects the remaining arguments */
+    for q := v.Car; rest == ""; {
+        if decl, ok = AsList(q); ok && decl == nil {
+            break
+        } else if ok {
+            args, q = append(args, string(self.compileParam(decl.Car, v))), decl.Cdr
+        } else {
+            rest = string(self.compileParam(q, v))
+        }
+    }
+
+    /* internal definitions are visible to the entire body */
+    self.scanDefines(proc)
+
+    /* compile the body first, it may define new local variables */
+    code := self.Compile(MakePair(Atom("begin"), proc))
+
+    /* construct a lambda expression */
+    p.fnp(OP_ldproc, &Proc {
+        Args  : args,
+        Rest  : rest,
+        Name  : name,
+        Code  : code,
+        Slots : env.Slots(),
+    })
+}
+
+func (self Compiler) compileParam(v Value, decl *List) Atom {
+    if at, ok := v.(Atom); !ok {
+        panic(self.error("malformed proc construct", decl))
+    } else if _, ok = self.env.defs[at]; ok {
+        panic(self.error(fmt.Sprintf("duplicated parameter `%s`", at), decl))
+    } else {
+        self.env.Bind(at)
+        return at
+    }
+}
+
+func (self Compiler) compileDefineSyntax(p *Program, v *List) {
+    var ok bool
+    var name Atom
+    var decl *List
+
+    /* check for define-syntax expression */
+    if v == nil                       { panic(self.error("malformed define-syntax construct", v)) }
+    if name, ok = v.Car.(Atom) ; !ok  { panic(self.error("malformed define-syntax construct", v)) }
+    if decl, ok = v.Cdr.(*List); !ok  { panic(self.error("malformed define-syntax construct", v)) }
+    if decl == nil || decl.Cdr != nil { panic(self.error("malformed define-syntax construct", v)) }
+
+    /* top-level macros are always named after the base name */
+    if env := self.env.Scope(); env.IsGlobal() {
+        name = self.env.stripAtom(name)
+    }
+
+    /* the macro is visible to it's own transformer */
+    self.env.Scope().BindMacro(name, self.compileSyntaxRules(name, decl.Car, self.env))
+    p.val(OP_ldconst, Unspecified{})
+}
+
+func (self Compiler) compileLetSyntax(p *Program, v *List, rec bool) {
+    var ok bool
+    var decl *List
+    var body *List
+
+    /* syntax bindings have it's own lexical environment, but not a runtime scope */
+    env := self.env.DeriveSyntax()
+
+    /* deconstruct the list, body cannot be empty */
+    if v == nil                      { panic(self.error("malformed let-syntax construct", v)) }
+    if decl, ok = AsList(v.Car); !ok { panic(self.error("malformed let-syntax construct", v)) }
+    if body, ok = AsList(v.Cdr); !ok { panic(self.error("malformed let-syntax construct", v)) }
+    if body == nil                   { panic(self.error("malformed let-syntax construct", v)) }
+
+    /* parse the declarations */
+    for r := decl; r != nil; {
+        var s Atom
+        var q *List
+
+        /* get the pair, and move to next item */
+        if q, ok = r.Car.(*List); !ok { panic(self.errorAt(decl, "malformed let-syntax construct", decl)) }
+        if r, ok = AsList(r.Cdr); !ok { panic(self.errorAt(decl, "malformed let-syntax construct", decl)) }
+        if s, ok = q.Car.(Atom) ; !ok { panic(self.errorAt(decl, "malformed let-syntax construct", decl)) }
+        if q, ok = q.Cdr.(*List); !ok { panic(self.errorAt(decl, "malformed let-syntax construct", decl)) }
+        if q == nil || q.Cdr != nil   { panic(self.errorAt(decl, "malformed let-syntax construct", decl)) }
+
+        /* `letrec-syntax` transformers are declared in the new environment */
+        if rec {
+            env.BindMacro(s, self.compileSyntaxRules(s, q.Car, env))
+        } else {
+            env.BindMacro(s, self.compileSyntaxRules(s, q.Car, self.env))
+        }
+    }
+
+    /* compile the body within the new environment */
+    self.env = env
+    self.compileBlock(p, body)
+}
+
+func (self Compiler) compileCondition(p *Program, v *List) {
+    var ok bool
+    var al *List
+    var pp *List
+
+    /* extract the condition and consequence clause */
+    if v == nil                     { panic(self.error("malformed if construct", v)) }
+    if pp, ok = v.Cdr.(*List) ; !ok { panic(self.error("malformed if construct", v)) }
+    if al, ok = AsList(pp.Cdr); !ok { panic(self.error("malformed if construct", v)) }
+    if al != nil && al.Cdr != nil   { panic(self.error("malformed if construct", v)) }
+
+    /* evaluate the condition expression */
+    self.compileValue(p, v.Car)
+    i := p.pc()
+    p.add(OP_if_false)
+
+    /* construct the "consequence" clause */
+    self.compileValue(p, pp.Car)
+    j := p.pc()
+    p.add(OP_goto)
+    p.pin(i)
+
+    /* check for the optional alternative clause */
+    if al == nil {
+        p.val(OP_ldconst, Unspecified{})
+        p.pin(j)
+        return
+    }
+
+    /* construct the alternative clause */
+    self.compileValue(p, al.Car)
+    p.pin(j)
+}
+
+func (self Compiler) compileShortCircuit(p *Program, v *List, kind RelKind) {
+    var ok bool
+    var pp *List
+    var br []int
+
+    /* empty condition */
+    if v == nil {
+        panic(self.error("empty condition"))
+    }
+
+    /* compile the first value */
+    self.compileValue(p, v.Car)
+    pp, ok = AsList(v.Cdr)
+
+    /* compile the remaining operands with combinators */
+    for ok && pp != nil {
+        car := pp.Car
+        cdr := pp.Cdr
+
+        /* select the combinator */
+        switch br = append(br, p.pc()); kind {
+            case Conjunctive : p.add(OP_assert_true)
+            case Disjunctive : p.add(OP_assert_false)
+            default          : panic("fatal: invalid relationship kind")
+        }
+
+        /* compile the value */
+        pp, ok = AsList(cdr)
+        self.compileValue(p, car)
+    }
+
+    /* check for list errors */
+    if !ok {
+        panic(self.error("malformed short-circuit construct", v))
+    }
+
+    /* pin all the branches */
+    for _, pc := range br {
+        p.pin(pc)
+    }
+}
+
+/** Lexical Environment Helpers **/
+
+func (self Compiler) isKeyword(v Atom, name Atom) bool {
+    at, ok := self.env.Keyword(v)
+    return ok && at == name
+}
+
+func (self Compiler) defineIdent(v Atom) (*Environ, Atom) {
+    if env := self.env.Scope(); !env.IsGlobal() {
+        env.Bind(v)
+        return env, v
+    } else {
+        v = self.env.stripAtom(v)
+        env.Unbind(v)
+        return env, v
+    }
+}
+
+func (self Compiler) address(env *Environ, name Atom) uint32 {
+    if depth, index := self.env.Address(env, name); depth > 0xffff || index > 0xffff {
+        panic(self.error("too many nested procs or local variables"))
+    } else {
+        return uint32(depth << 16 | index)
+    }
+}
+
+func (self Compiler) scanDefines(v *List) {
+    for ok := true; ok && v != nil; v, ok = AsList(v.Cdr) {
+        var at Atom
+        var vv *List
+
+        /* only care about `define` and `begin` forms */
+        if vv, ok = v.Car.(*List)  ; !ok || vv == nil { continue }
+        if at, ok = vv.Car.(Atom)  ; !ok              { continue }
+        if vv, ok = vv.Cdr.(*List) ; !ok || vv == nil { continue }
+
+        /* bind the defined name, and splice the `begin` blocks */
+        switch {
+            case self.isKeyword(at, "begin")  : self.scanDefines(vv)
+            case self.isKeyword(at, "define") : self.scanDefine(vv.Car)
+        }
+    }
+}
+
+func (self Compiler) scanDefine(v Value) {
+    if at, ok := v.(Atom); ok {
+        self.env.Bind(at)
+    } else if vv, ok := v.(*List); ok && vv != nil {
+        self.scanDefine(vv.Car)
+    }
+}
+
+/** Quasi-quoting Helpers **/
+
+func (self Compiler) quasiForm(v Value) (Atom, *List, bool) {
+    var ok bool
+    var at Atom
+    var vv *List
+    var sv *List
+
+    /* must be a list of exact 2 elements */
+    if vv, ok = v.(*List)     ; !ok       { return "", nil, false }
+    if at, ok = vv.Car.(Atom) ; !ok       { return "", nil, false }
+    if sv, ok = vv.Cdr.(*List); !ok       { return "", nil, false }
+    if sv.Cdr != nil                      { return "", nil, false }
+    if at, ok = self.env.Keyword(at); !ok { return "", nil, false }
+
+    /* check for quasi-quoting keywords */
+    switch at {
+        case "quasiquote"       : return at, sv, true
+        case "unquote"          : return at, sv, true
+        case "unquote-splicing" : return at, sv, true
+        default                 : return "", nil, false
+    }
+}
+
+func (self Compiler) hasUnquote(v Value) bool {
+    switch vv := v.(type) {
+        case *List   : return self.hasUnquoteList(vv)
+        case *Vector : return self.hasUnquoteList(MakeList(vv.Elems...))
+        default      : return false
+    }
+}
+
+func (self Compiler) hasUnquoteList(v *List) bool {
+    if v == nil {
+        return false
+    } else if at, _, ok := self.quasiForm(v); ok && at != "quasiquote" {
+        return true
+    } else {
+        return self.hasUnquote(v.Car) || self.hasUnquote(v.Cdr)
+    }
+}
+
+/** Syntax Desugaring **/
+
+func (self Compiler) desugarDo(v *List) *List {
+    var decl *List
+    var cond *List
+    var body *List
+    var defs []Value
+    var init []Value
+    var step []Value
+
+    /* list header */
+    p := v
+    ok := false
+
+    /* deconstruct the list */
+    if p == nil                      { panic(self.error("malformed do construct", v)) }
+    if decl, ok = p.Car.(*List); !ok { panic(self.error("malformed do construct", v)) }
+    if p   , ok = p.Cdr.(*List); !ok { panic(self.error("malformed do construct", v)) }
+    if cond, ok = p.Car.(*List); !ok { panic(self.error("malformed do construct", v)) }
+    if body, ok = p.Cdr.(*List); !ok { panic(self.error("malformed do construct", v)) }
+
+    /* parse the declarations */
+    for p = decl; p != nil; {
+        var s Atom
+        var q *List
+        var i Value
+        var r Value
+
+        /* get the initialization list, and move to next item */
+        if q, ok = p.Car.(*List); !ok { panic(self.errorAt(decl, "malformed do construct", decl)) }
+        if p, ok = AsList(p.Cdr); !ok { panic(self.errorAt(decl, "malformed do construct", decl)) }
+        if s, ok = q.Car.(Atom) ; !ok { panic(self.errorAt(decl, "malformed do construct", decl)) }
+        if q, ok = q.Cdr.(*List); !ok { panic(self.errorAt(decl, "malformed do construct", decl)) }
+
+        /* check for the optional "step" part */
+        if i, r = q.Car, s; q.Cdr != nil {
+            if q, ok = q.Cdr.(*List); !ok { panic(self.errorAt(decl, "malformed do construct", decl)) }
+            if r, ok = q.Car.(*List); !ok { panic(self.errorAt(decl, "malformed do construct", decl)) }
+            if q.Cdr != nil               { panic(self.errorAt(decl, "malformed do construct", decl)) }
+        }
+
+        /* add to initialzer list */
+        defs = append(defs, s)
+        init = append(init, i)
+        step = append(step, r)
+    }
+
+    /* check the condition expression */
+    if p, ok = AsList(cond.Cdr); !ok { panic(self.errorAt(cond, "malformed do construct", cond)) }
+    if p != nil && p.Cdr != nil      { panic(self.errorAt(cond, "malformed do construct", cond)) }
+
+    /* rebuild the "do" construct */
+    if p == nil {
+        return self.rebuildDo(defs, init, step, cond.Car, nil, body)
+    } else {
+        return self.rebuildDo(defs, init, step, cond.Car, p.Car, body)
+    }
+}
+
+func (self Compiler) desugarLet(v *List, kind LetKind) *List {
+    var decl *List
+    var body *List
+    var defs []Value
+    var init []Value
+
+    /* list header */
+    p := v
+    n := 0
+    ok := false
+
+    /* deconstruct the list, body cannot be empty */
+    if p == nil                      { panic(self.error("malformed let construct", v)) }
+    if decl, ok = AsList(p.Car); !ok { panic(self.error("malformed let construct", v)) }
+    if body, ok = p.Cdr.(*List); !ok { panic(self.error("malformed let construct", v)) }
+
+    /* parse the declarations */
+    for p = decl; p != nil; n++ {
+        var s Atom
+        var q *List
+
+        /* get the pair, and move to next item */
+        if q, ok = p.Car.(*List); !ok { panic(self.errorAt(decl, "malformed let construct", decl)) }
+        if p, ok = AsList(p.Cdr); !ok { panic(self.errorAt(decl, "malformed let construct", decl)) }
+        if s, ok = q.Car.(Atom) ; !ok { panic(self.errorAt(decl, "malformed let construct", decl)) }
+        if q, ok = q.Cdr.(*List); !ok { panic(self.errorAt(decl, "malformed let construct", decl)) }
+        if q.Cdr != nil               { panic(self.errorAt(decl, "malformed let construct", decl)) }
+
+        /* add to initializer list */
+        defs = append(defs, s)
+        init = append(init, q.Car)
+    }
+
+    /* need special handling of `letrec` */
+    switch kind {
+        case Let     : break
+        case LetRec  : return self.rebuildLetRec(defs, init, body)
+        case LetStar : n = 1
+        default      : panic("fatal: invalid let kind")
+    }
+
+    /* rebuild as immediate lambda application expression */
+    for i := len(defs) - n; i >= 0; i-- {
+        arg := MakeList(defs[i:i + n]...)
+        ref := MakePair(Atom(Lambda), MakePair(arg, body))
+        body = MakeList(MakeList(append([]Value{ref}, init[i:i + n]...)...))
+    }
+
+    /* all done */
+    return body.Car.(*List)
+}
+
+/** Core Language Rebuilding **/
+
+func (self Compiler) rebuildDo(defs []Value, init []Value, step []Value, cond Value, retv Value, body *List) *List {
+    var pb, qb *List
+    var pd, qd *List
+    var pi, qi *List
+    var ps, qs *List
+
+    /* construct an unique name */
+    ok := true
+    name := fmt.Sprintf("#[desugar-do-%d]", self.env.ids.Next())
+
+    /* loop variable, initial values and stepping */
+    for _, v := range defs { AppendValue(&pd, &qd, v) }
+    for _, v := range init { AppendValue(&pi, &qi, v) }
+    for _, v := range step { AppendValue(&ps, &qs, v) }
+
+    /* copy the body */
+    for p := body; ok && p != nil; p, ok = AsList(p.Cdr) {
+        AppendValue(&pb, &qb, p.Car)
+    }
+
+    /* check for loop body */
+    if !ok || body == nil {
+        panic(self.error("loop body must be a proper list", body))
+    }
+
+    /* return an empty list if not specified */
+    if retv == nil {
+        retv = MakeList(Atom("quote"), nil)
+    }
+
+    /* append the loop recursion */
+    AppendValue(&pb, &qb, MakePair(
+        Atom(name),
+        ps,
+    ))
+
+    /* recursive lambda body */
+    loop := MakeList(
+        Atom("if"),
+        cond,
+        retv,
+        MakePair(Atom("begin"), pb),
+    )
+
+    /* reconstruct "do" with "letrec" */
+    return MakePair(Atom("letrec"), MakePair(
+        MakeList(MakeList(Atom(name), MakeList(Atom(Lambda), pd, loop))),
+        MakeList(MakePair(Atom(name), pi)),
+    ))
+}
+
+func (self Compiler) rebuildLetRec(defs []Value, init []Value, body *List) *List {
+    var pd, qd *List
+    var pi, qi *List
+
+    /* variable definations */
+    for _, v := range defs {
+        AppendValue(&pd, &qd, MakeList(v, MakeList(Atom("quote"), nil)))
+    }
+
+    /* set initial values */
+    for i, v := range init {
+        AppendValue(&pi, &qi, MakeList(Atom("set!"), defs[i], v))
+    }
+
+    /* reconstruct "letrec" with "let" and "set!" */
+    qi.Cdr = body
+    return MakePair(Atom("let"), MakePair(pd, pi))
+}
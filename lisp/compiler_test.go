@@ -0,0 +1,99 @@
+package lisp
+
+import (
+    `testing`
+
+    `github.com/stretchr/testify/require`
+)
+
+func stmt(s string) *List {
+    return CreateParser(s).Parse().Cdr.(*List).Car.(*List)
+}
+
+func TestCompiler_Desugar(t *testing.T) {
+    tests := []struct{
+        fn func(Compiler, *List) *List
+        ss string
+    } {{
+        fn: Compiler.desugarDo,
+        ss: `(do ((a 0 (+ a 1))
+                  (b 0 (+ b 1)))
+                 ((and (> a 10) (> b 10)) (+ a b))
+                (display a)
+                (display #\space)
+                (display b)
+                (newline))`,
+    }, {
+        fn: func(c Compiler, s *List) *List { return c.desugarLet(s, Let) },
+        ss: `(let ((a 1) (b 2)) (display (+ a b)) (newline))`,
+    }, {
+        fn: func(c Compiler, s *List) *List { return c.desugarLet(s, LetRec) },
+        ss: `(letrec ((fac (λ (v r)
+                              (if (= v 0) r (fac (- v 1) (* v r))))))
+                (display (fac 10 1))
+                (newline))`,
+    }, {
+        fn: func(c Compiler, s *List) *List { return c.desugarLet(s, LetStar) },
+        ss: `(let* ((a 1) (b 2)) (display (+ a b)) (newline))`,
+    }}
+    for _, ts := range tests {
+        println(ts.fn(Compiler{env: CreateEnviron()}, stmt(ts.ss).Cdr.(*List)).String())
+    }
+}
+
+func TestCompiler_Compile(t *testing.T) {
+    src := `
+        (let ((r 1))
+            (do ((i 1 (+ i 1))) ((> i 10) r)
+                (set! r (* r i)))
+            (display r)
+            (newline))
+    `
+    println(Compiler{}.Compile(CreateParser(src).Parse()).String())
+}
+
+func TestCompiler_Diagnostics(t *testing.T) {
+    ps := CreateNamedParser("test.scm", "(display 1)\n(define (f x)\n  (let ((a 1) (b)) a))")
+    src := ps.Parse()
+    require.PanicsWithError(t, "test.scm:3:8: compile: malformed let construct: ((a 1) (b))", func() {
+        Compiler{Spans: ps.Spans()}.Compile(src)
+    })
+}
+
+func TestCompiler_LexicalAddress(t *testing.T) {
+    prog := Compiler{}.Compile(CreateParser("(define g 1) (lambda (x) (lambda (y) (set! x y) (+ x y g)))").Parse())
+    outer := prog[3].Fn()
+    inner := outer.Code[0].Fn()
+    require.Equal(t, 1, outer.Slots)
+    require.Equal(t, 1, inner.Slots)
+    require.Equal(t, "ldlocal     [0, 0]", inner.Code[0].String())
+    require.Equal(t, "stlocal     [1, 0]", inner.Code[1].String())
+    require.Equal(t, "ldlocal     [1, 0]", inner.Code[4].String())
+    require.Equal(t, "ldvar       g", inner.Code[6].String())
+}
+
+func TestCompiler_Primitive(t *testing.T) {
+    prog := Compiler{NoOptimize: true}.Compile(CreateParser("(+ 1 2) (+ 1 2 3) (let ((+ -)) (+ 1 2))").Parse())
+    require.Equal(t, "add", prog[2].String())
+    require.Equal(t, "apply       #4", prog[8].String())
+    require.Equal(t, "ldlocal     [0, 0]", prog[10].Fn().Code[0].String())
+    require.Equal(t, "tailcall    #3", prog[10].Fn().Code[3].String())
+}
+
+func TestCompiler_SourceLines(t *testing.T) {
+    ps := CreateParser("(define (f x)\n  (if (> x 1)\n      (* x\n         (f (- x 1)))\n      1))")
+    src := ps.Parse()
+    prog := Compiler{Spans: ps.Spans()}.Compile(src)
+    code := prog[0].Fn().Code
+    require.Equal(t, 1, prog[0].Line())
+    require.Equal(t, []int { 2, 2, 2, 2, 3, 4, 4, 4, 4, 4, 3, 2, 2, 0 }, func() (ret []int) {
+        for _, iv := range code { ret = append(ret, iv.Line()) }
+        return
+    }())
+    iv := mkins(OP_apply, 2, "", nil, nil).withLine(3)
+    iv.setOp(OP_tailcall)
+    require.Equal(t, OP_tailcall, iv.Op())
+    require.Equal(t, 3, iv.Line())
+    require.Contains(t, prog.Disassemble(true), " 9 :  apply       #2       ; line 4\n")
+    require.NotContains(t, prog.Disassemble(false), "; line")
+}
@@ -0,0 +1,286 @@
+package lisp
+
+import (
+    `fmt`
+)
+
+type Control struct {
+    it   *Interpreter
+    Name string
+    Proc func(*Machine, []Value, bool)
+}
+
+var (
+    controlTab = make(map[string]*Control)
+)
+
+func RegisterControl(name string, proc func(*Machine, []Value, bool)) {
+    if _, ok := controlTab[name]; ok {
+        panic("registry: duplicated control proc: " + name)
+    } else {
+        controlTab[name] = &Control { Name: name, Proc: proc }
+    }
+}
+
+func (self *Control) Call(args []Value) Value {
+    return Apply(self, args)
+}
+
+func (self *Control) String() string {
+    return fmt.Sprintf("#[control-%s]", self.Name)
+}
+
+func (self *Control) IsIdentity() bool {
+    return true
+}
+
+/** Continuations **/
+
+type Continuation struct {
+    vm   *Machine
+    fp   *_Frame
+    wind *_Winder
+    hand *_Handler
+}
+
+type _Escape struct {
+    cont *Continuation
+    retv Value
+}
+
+func (self *Continuation) Call(args []Value) Value {
+    return Apply(self, args)
+}
+
+func (self *Continuation) String() string {
+    return "#[continuation]"
+}
+
+func (self *Continuation) IsIdentity() bool {
+    return true
+}
+
+func (self *Continuation) resume(vm *Machine, args []Value) {
+    var rv Value
+    var ok bool
+
+    /* continuations accept at most 1 value */
+    switch len(args) {
+        case 0  : ok = true
+        case 1  : ok, rv = true, args[0]
+        default : ok = false
+    }
+
+    /* check for arguments */
+    if !ok {
+        panic(MakeError(ErrArity, "continuation: proc takes at most 1 argument"))
+    }
+
+    /* the Go callers of a finished nested machine are gone, only top-level machines can be re-entered after finished */
+    if self.vm != vm && !self.vm.live {
+        if self.vm.up != nil {
+            panic(MakeError(ErrRuntime, "continuation: cannot re-enter a nested call that has already returned"))
+        } else if vm.up != nil {
+            panic(MakeError(ErrRuntime, "continuation: cannot re-enter a finished top-level form from a nested call"))
+        }
+    }
+
+    /* run the `before` and `after` thunks, then restore the exception handlers */
+    vm.it.rewind(self.wind)
+    vm.it.handlers = self.hand
+
+    /* the machine that captures this continuation is still running, unwind the Go stack first */
+    if self.vm != vm && self.vm.live {
+        panic(&_Escape { cont: self, retv: rv })
+    } else {
+        self.reinstate(vm, rv)
+    }
+}
+
+func (self *Continuation) reinstate(vm *Machine, rv Value) {
+    vm.fp = self.fp.clone()
+    vm.ret(rv)
+}
+
+/** Dynamic Wind **/
+
+type _Winder struct {
+    prev   *_Winder
+    depth  int
+    before Value
+    after  Value
+}
+
+func (self *_Winder) level() int {
+    if self == nil {
+        return 0
+    } else {
+        return self.depth
+    }
+}
+
+func (self *Interpreter) rewind(to *_Winder) {
+    var path []*_Winder
+    var from = self.winds
+
+    /* leave all the extra extents */
+    for from.level() > to.level() {
+        self.winds = from.prev
+        Apply(from.after, nil)
+        from = from.prev
+    }
+
+    /* record all the extents to enter */
+    for to.level() > from.level() {
+        path = append(path, to)
+        to = to.prev
+    }
+
+    /* find the common ancestor */
+    for from != to {
+        self.winds = from.prev
+        Apply(from.after, nil)
+        path = append(path, to)
+        from, to = from.prev, to.prev
+    }
+
+    /* enter the extents from the outer-most one */
+    for i := len(path) - 1; i >= 0; i-- {
+        Apply(path[i].before, nil)
+        self.winds = path[i]
+    }
+}
+
+func intrinsicsWindEnter(it *Interpreter, args []Value) Value {
+    if len(args) != 2 {
+        panic(MakeError(ErrArity, "%wind-enter: proc takes exact 2 arguments"))
+    }
+
+    /* push the extent */
+    it.winds = &_Winder {
+        prev   : it.winds,
+        depth  : it.winds.level() + 1,
+        before : args[0],
+        after  : args[1],
+    }
+
+    /* all done */
+    return nil
+}
+
+func intrinsicsWindLeave(it *Interpreter, args []Value) Value {
+    if len(args) != 0 {
+        panic(MakeError(ErrArity, "%wind-leave: proc takes no arguments"))
+    } else if it.winds == nil {
+        panic("fatal: unbalanced dynamic extents")
+    } else {
+        it.winds = it.winds.prev
+        return nil
+    }
+}
+
+/** Exception Handlers **/
+
+type _Handler struct {
+    prev *_Handler
+    proc Value
+}
+
+var _Trampoline = func() (p Program) {
+    p.i32(OP_apply, 2)
+    p.add(OP_return)
+    return
+}()
+
+func uncaught(obj Value) *LispError {
+    if ev, ok := obj.(*LispError); ok {
+        return ev
+    } else {
+        return MakeError(ErrRuntime, "uncaught exception", obj)
+    }
+}
+
+func (self *Machine) after(fn func(Value) Value) {
+    self.push(&_Frame {
+        code : _Trampoline,
+        st   : []Value { &Intrinsic { Name: "%trampoline", Proc: func(args []Value) Value { return fn(args[0]) } } },
+    })
+}
+
+func (self *Machine) raise(obj Value, continuable bool) {
+    hd := self.it.handlers
+
+    /* handlers without procs propagate errors as Go panics */
+    if hd == nil || hd.proc == nil {
+        panic(uncaught(obj))
+    }
+
+    /* the handler is called with the outer handlers installed */
+    if self.it.handlers = hd.prev; continuable {
+        self.after(func(rv Value) Value { self.it.handlers = hd; return rv })
+    } else {
+        self.after(func(Value) Value { panic(MakeError(ErrRuntime, "raise: handler returned from non-continuable exception", obj)) })
+    }
+
+    /* call the handler with the raised object */
+    self.apply(hd.proc, []Value{obj}, false)
+}
+
+/** Control Procs **/
+
+func controlCallCC(vm *Machine, args []Value, tail bool) {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "call-with-current-continuation: proc takes exact 1 argument"))
+    }
+
+    /* capture the current continuation */
+    cc := &Continuation {
+        vm   : vm,
+        fp   : vm.fp.clone(),
+        wind : vm.it.winds,
+        hand : vm.it.handlers,
+    }
+
+    /* call the proc with the continuation */
+    vm.apply(args[0], []Value{cc}, tail)
+}
+
+func controlRaise(vm *Machine, args []Value, _ bool) {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "raise: proc takes exact 1 argument"))
+    } else {
+        vm.raise(args[0], false)
+    }
+}
+
+func controlRaiseContinuable(vm *Machine, args []Value, _ bool) {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "raise-continuable: proc takes exact 1 argument"))
+    } else {
+        vm.raise(args[0], true)
+    }
+}
+
+func controlWithExceptionHandler(vm *Machine, args []Value, _ bool) {
+    if len(args) != 2 {
+        panic(MakeError(ErrArity, "with-exception-handler: proc takes exact 2 arguments"))
+    }
+
+    /* install the handler */
+    hd := vm.it.handlers
+    vm.it.handlers = &_Handler { prev: hd, proc: asCallable("with-exception-handler", args[0]) }
+
+    /* call the thunk, and restore the handlers when it returns */
+    vm.after(func(rv Value) Value { vm.it.handlers = hd; return rv })
+    vm.apply(args[1], nil, false)
+}
+
+func init() {
+    RegisterControl("raise", controlRaise)
+    RegisterControl("raise-continuable", controlRaiseContinuable)
+    RegisterControl("with-exception-handler", controlWithExceptionHandler)
+    RegisterControl("call/cc", controlCallCC)
+    RegisterControl("call-with-current-continuation", controlCallCC)
+    RegisterBoundIntrinsic("%wind-enter", intrinsicsWindEnter)
+    RegisterBoundIntrinsic("%wind-leave", intrinsicsWindLeave)
+}
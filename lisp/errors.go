@@ -0,0 +1,114 @@
+package lisp
+
+import (
+    `fmt`
+    `runtime`
+    `strings`
+)
+
+type ErrorKind uint8
+
+const (
+    ErrRuntime ErrorKind = iota
+    ErrSyntax
+    ErrCompile
+    ErrType
+    ErrArity
+    ErrUnbound
+    ErrIO
+    ErrUser
+    ErrVerify
+    ErrRange
+)
+
+var _ErrorKindTab = [...]string {
+    ErrRuntime : "runtime",
+    ErrSyntax  : "syntax",
+    ErrCompile : "compile",
+    ErrType    : "type",
+    ErrArity   : "arity",
+    ErrUnbound : "unbound",
+    ErrIO      : "io",
+    ErrUser    : "user",
+    ErrVerify  : "verify",
+    ErrRange   : "range",
+}
+
+func (self ErrorKind) String() string {
+    if int(self) < len(_ErrorKindTab) {
+        return _ErrorKindTab[self]
+    } else {
+        return fmt.Sprintf("ErrorKind(%d)", self)
+    }
+}
+
+type LispError struct {
+    Kind      ErrorKind
+    Message   string
+    Irritants []Value
+    Location  Span
+}
+
+func MakeError(kind ErrorKind, msg string, irritants ...Value) *LispError {
+    return &LispError {
+        Kind      : kind,
+        Message   : msg,
+        Irritants : irritants,
+    }
+}
+
+func AsError(v interface{}) *LispError {
+    switch vv := v.(type) {
+        case *LispError    : return vv
+        case runtime.Error : panic(vv)
+        case string        : return MakeError(ErrRuntime, vv)
+        case error         : return MakeError(ErrRuntime, vv.Error())
+        default            : return MakeError(ErrRuntime, fmt.Sprint(v))
+    }
+}
+
+func CatchError(fn func()) (err error) {
+    defer func() {
+        if v := recover(); v != nil {
+            err = AsError(v)
+        }
+    }()
+    fn()
+    return nil
+}
+
+func (self *LispError) At(sp Span) *LispError {
+    self.Location = sp
+    return self
+}
+
+func (self *LispError) Error() string {
+    var buf []string
+    var arg []string
+
+    /* location comes first, if any */
+    if self.Location.IsValid() {
+        buf = append(buf, self.Location.String())
+    }
+
+    /* format all the irritants */
+    for _, v := range self.Irritants {
+        arg = append(arg, AsString(v))
+    }
+
+    /* append the irritants after the message */
+    if buf = append(buf, self.Message); len(arg) != 0 {
+        buf = append(buf, strings.Join(arg, " "))
+    }
+
+    /* build the error message */
+    return strings.Join(buf, ": ")
+}
+
+func (self *LispError) String() string {
+    return fmt.Sprintf("#[%s-error %q]", self.Kind, self.Error())
+}
+
+func (self *LispError) IsIdentity() bool {
+    return true
+}
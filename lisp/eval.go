@@ -0,0 +1,594 @@
+package lisp
+
+import (
+    `fmt`
+)
+
+type Scope struct {
+    it   *Interpreter
+    defs map[string]*Cell
+}
+
+type Cell struct {
+    Name  string
+    Value Value
+    scope *Scope
+    prim  *_Primitive
+    bound bool
+}
+
+func CreateGlobalScope() *Scope {
+    return CreateInterpreter().scope
+}
+
+func (self *Scope) Get(key string) (Value, bool) {
+    if cc, ok := self.defs[key]; !ok || !cc.bound {
+        return nil, false
+    } else {
+        return cc.Value, true
+    }
+}
+
+func (self *Scope) Set(key string, val Value) {
+    self.Cell(key).define(val)
+}
+
+func (self *Scope) Cell(key string) *Cell {
+    if cc, ok := self.defs[key]; ok {
+        return cc
+    } else {
+        cc = &Cell { Name: key, scope: self, prim: primitiveOf(key) }
+        self.defs[key] = cc
+        return cc
+    }
+}
+
+/** Global Linkage **/
+
+type _Links struct {
+    cells []*Cell
+    procs []*_Links
+}
+
+func newLinks(p Program) *_Links {
+    return &_Links {
+        cells: make([]*Cell, len(p)),
+    }
+}
+
+func (self *_Links) proc(pc int, fn *Proc) *_Links {
+    if self.procs == nil {
+        self.procs = make([]*_Links, len(self.cells))
+    }
+
+    /* procs loaded by the same instruction share the same linkage */
+    if self.procs[pc] == nil {
+        self.procs[pc] = newLinks(fn.Code)
+    }
+
+    /* all done */
+    return self.procs[pc]
+}
+
+func (self *Cell) load() Value {
+    if !self.bound {
+        panic(MakeError(ErrUnbound, "eval: undefined reference", Atom(self.Name)))
+    } else {
+        return self.Value
+    }
+}
+
+func (self *Cell) store(v Value) {
+    if !self.bound {
+        panic(MakeError(ErrUnbound, "eval: undefined reference", Atom(self.Name)))
+    } else {
+        self.Value = v
+        self.rebind(v)
+    }
+}
+
+func (self *Cell) define(v Value) {
+    self.Value = v
+    self.bound = true
+    self.rebind(v)
+}
+
+func (self *Cell) rebind(v Value) {
+    if self.prim != nil && v != self.prim.intr {
+        self.scope.it.rebound |= self.prim.mask
+    }
+}
+
+type Locals struct {
+    prev *Locals
+    vals []Value
+}
+
+type (
+    _Locals2 struct { Locals; buf [2]Value }
+    _Locals4 struct { Locals; buf [4]Value }
+    _Locals8 struct { Locals; buf [8]Value }
+)
+
+func newLocals(slots int) *Locals {
+    switch {
+        case slots == 0 : return new(Locals)
+        case slots <= 2 : p := new(_Locals2); p.vals = p.buf[:slots]; return &p.Locals
+        case slots <= 4 : p := new(_Locals4); p.vals = p.buf[:slots]; return &p.Locals
+        case slots <= 8 : p := new(_Locals8); p.vals = p.buf[:slots]; return &p.Locals
+        default         : return &Locals { vals: make([]Value, slots) }
+    }
+}
+
+func arityError(proc *Proc, argv int) *LispError {
+    switch argc := len(proc.Args); {
+        case proc.IsVariadic() && argc == 1 : return MakeError(ErrArity, fmt.Sprintf("eval: proc %s requires at least 1 argument, got %d", proc.Name, argv))
+        case proc.IsVariadic()              : return MakeError(ErrArity, fmt.Sprintf("eval: proc %s requires at least %d arguments, got %d", proc.Name, argc, argv))
+        case argc == 0                      : return MakeError(ErrArity, fmt.Sprintf("eval: proc %s takes no arguments, got %d", proc.Name, argv))
+        case argc == 1                      : return MakeError(ErrArity, fmt.Sprintf("eval: proc %s takes exact 1 argument, got %d", proc.Name, argv))
+        default                             : return MakeError(ErrArity, fmt.Sprintf("eval: proc %s takes exact %d arguments, got %d", proc.Name, argc, argv))
+    }
+}
+
+func (self *Locals) Derive(proc *Proc, vals []Value) (ret *Locals) {
+    argv := len(vals)
+    argc := len(proc.Args)
+
+    /* check for args */
+    if (!proc.IsVariadic() && argv != argc) || argv < argc {
+        panic(arityError(proc, argv))
+    }
+
+    /* arguments occupy the first few slots, small slot arrays are allocated along with the locals */
+    ret = newLocals(proc.Slots)
+    ret.prev = self
+    copy(ret.vals, vals[:argc])
+
+    /* the remaining arguments are collected into a list */
+    if proc.IsVariadic() {
+        ret.vals[argc] = MakeList(vals[argc:]...)
+    }
+
+    /* all done */
+    return
+}
+
+func (self *Locals) Lookup(depth int) *Locals {
+    for ; depth > 0; depth-- { self = self.prev }
+    return self
+}
+
+func (self *Scope) initAsGlobal() {
+    for k, v := range self.it.intrinsics {
+        self.Set(k, v)
+    }
+
+    /* control procs */
+    for k, v := range self.it.controls {
+        self.Set(k, v)
+    }
+
+    /* procs defined by the prelude, the compiled prelude is shared, but it is linked separately for each interpreter */
+    self.it.evaluate(_Prelude)
+}
+
+func sttop(st []Value) Value {
+    if nb := len(st); nb == 0 {
+        panic("fatal: stack underflow")
+    } else {
+        return st[nb - 1]
+    }
+}
+
+func stpop(st *[]Value) (v Value) {
+    if i := len(*st) - 1; i < 0 {
+        panic("fatal: stack underflow")
+    } else {
+        v, *st = (*st)[i], (*st)[:i]
+        return
+    }
+}
+
+func stsub(st []Value, vv Value) {
+    st[len(st) - 1] = vv
+}
+
+func strem(st *[]Value, nb int) (v []Value) {
+    if nb == 0 {
+        panic("fatal: taking nothing")
+    } else if i := len(*st) - nb; i < 0 {
+        panic("fatal: stack underflow")
+    } else {
+        v, *st = (*st)[i:], (*st)[:i]
+        return
+    }
+}
+
+func istrue(v Value) bool {
+    switch vv := v.(type) {
+        case nil     : return false
+        case Int     : return vv != 0
+        case Bool    : return bool(vv)
+        case Char    : return vv != 0
+        case Float   : return vv != 0.0
+        case String  : return vv != ""
+        case Complex : return vv != 0
+        default      : return true
+    }
+}
+
+type Machine struct {
+    it   *Interpreter
+    fp   *_Frame
+    rv   Value
+    up   *Machine
+    base int
+    nest int
+    live bool
+}
+
+type _Frame struct {
+    prev  *_Frame
+    code  Program
+    pc    int
+    depth int
+    env   *Scope
+    vars  *Locals
+    links *_Links
+    st    []Value
+}
+
+func (self *_Frame) clone() (ret *_Frame) {
+    p := &ret
+    q := self
+
+    /* copy every frame, including the operand stacks */
+    for q != nil {
+        *p = new(_Frame)
+        **p = *q
+        (*p).st = append(make([]Value, 0, cap(q.st)), q.st...)
+        p, q = &(*p).prev, q.prev
+    }
+
+    /* all done */
+    return
+}
+
+func (self *_Frame) link(pc int) *Cell {
+    if cc := self.links.cells[pc]; cc != nil {
+        return cc
+    } else {
+        cc = self.env.Cell(self.code[pc].Sv())
+        self.links.cells[pc] = cc
+        return cc
+    }
+}
+
+func Evaluate(s *Scope, p Program) Value {
+    return s.it.evaluate(p)
+}
+
+func Apply(fn Value, args []Value) Value {
+    switch fv := fn.(type) {
+        case LoadedProc    : return fv.Scope.it.apply(fv, args)
+        case *Control      : return fv.it.apply(fv, args)
+        case *Continuation : return fv.vm.it.apply(fv, args)
+        case Callable      : return fv.Call(args)
+        default            : panic(MakeError(ErrType, "eval: object is not appliable", fn))
+    }
+}
+
+func (self *Machine) depth() int {
+    if self.fp == nil {
+        return self.base
+    } else {
+        return self.fp.depth
+    }
+}
+
+func (self *Machine) push(fp *_Frame) {
+    if fp.depth = self.depth() + 1; fp.depth > self.it.MaxStackDepth {
+        panic(MakeError(ErrRuntime, "eval: stack overflow"))
+    } else {
+        fp.prev, self.fp = self.fp, fp
+    }
+}
+
+func (self *Machine) ret(v Value) {
+    if self.fp == nil {
+        self.rv = v
+    } else {
+        self.fp.st = append(self.fp.st, v)
+    }
+}
+
+func (self *Machine) frame() *_Frame {
+    if fp := self.it.frames; fp == nil {
+        return &_Frame { st: make([]Value, 0, 16) }
+    } else {
+        self.it.frames, fp.prev = fp.prev, nil
+        self.it.nfree--
+        return fp
+    }
+}
+
+func (self *Machine) release(fp *_Frame) {
+    if self.it.nfree < MaxFreeFrames {
+        *fp = _Frame { prev: self.it.frames, st: fp.st[:0] }
+        self.it.frames = fp
+        self.it.nfree++
+    }
+}
+
+func (self *Machine) enter(fn LoadedProc, args []Value, tail bool) {
+    fp := self.frame()
+    fp.code = fn.Code
+    fp.env = fn.Scope
+    fp.vars = fn.Locals.Derive(fn.Proc, args)
+    fp.links = fn.links
+
+    /* tail-calls replace the current frame, which is never referenced again since continuations copy the frames */
+    if old := self.fp; !tail || old == nil {
+        self.push(fp)
+    } else if len(old.st) != 0 {
+        panic("fatal: unbalanced stack when tail-call")
+    } else {
+        fp.prev, fp.depth, self.fp = old.prev, old.depth, fp
+        self.release(old)
+    }
+}
+
+func (self *Machine) apply(fn Value, args []Value, tail bool) {
+    for {
+        switch fv := fn.(type) {
+            default: {
+                panic(MakeError(ErrType, "eval: object is not appliable", fn))
+            }
+
+            /* loaded procs are executed within a new frame */
+            case LoadedProc: {
+                self.enter(fv, args, tail)
+                return
+            }
+
+            /* intrinsics may request tail-calls instead of returning values */
+            case *Intrinsic: {
+                rv := fv.Proc(args)
+                tc, ok := rv.(*TailCall)
+
+                /* continue with the requested proc if any */
+                if !ok {
+                    self.ret(rv)
+                    return
+                } else {
+                    fn, args = tc.Proc, tc.Args
+                }
+            }
+
+            /* control procs have full access to the machine */
+            case *Control: {
+                fv.Proc(self, args, tail)
+                return
+            }
+
+            /* continuations replaces the entire machine state */
+            case *Continuation: {
+                fv.resume(self, args)
+                return
+            }
+
+            /* other callable objects */
+            case Callable: {
+                self.ret(fv.Call(args))
+                return
+            }
+        }
+    }
+}
+
+func (self *Machine) run() Value {
+    self.live, self.it.active = true, self
+    defer func() { self.live, self.it.active = false, self.up }()
+
+    /* continuation escaping from nested machines or raised errors would restart the execution */
+    for !self.exec() {}
+    return self.rv
+}
+
+func (self *Machine) exec() (done bool) {
+    defer func() {
+        if v := recover(); v != nil {
+            if esc, ok := v.(*_Escape); !ok {
+                self.failed(v)
+            } else if esc.cont.vm == self {
+                esc.cont.reinstate(self, esc.retv)
+            } else {
+                panic(v)
+            }
+        }
+    }()
+
+    /* execute the machine until finished */
+    self.loop()
+    return true
+}
+
+func (self *Machine) failed(v interface{}) {
+    fp := self.fp
+    err, ok := v.(*LispError)
+
+    /* wrapped errors are located with the line of the failing instruction */
+    if !ok {
+        if err = AsError(v); fp != nil && fp.pc > 0 && fp.pc <= len(fp.code) && fp.code[fp.pc - 1].Line() != 0 {
+            err.Location = Span { Row: fp.code[fp.pc - 1].Line() }
+        }
+    }
+
+    /* pass the error to the handler if any, otherwise propagate it */
+    if self.it.handlers != nil && self.it.handlers.proc != nil {
+        self.raise(err, false)
+    } else {
+        panic(err)
+    }
+}
+
+func (self *Machine) folded(fp *_Frame, pc int, iv Instr) {
+    fv := iv.Rv().(*_Folded)
+
+    /* the folded value is valid only if none of the primitive operators were rebound */
+    if iv.Iv() & self.it.rebound == 0 {
+        fp.st = append(fp.st, fv.val)
+    } else {
+        self.enter(fv.fn.load(fp.env, fp.vars, fp.links.proc(pc, fv.fn)), nil, false)
+    }
+}
+
+func (self *Machine) loop() {
+    for self.fp != nil {
+        fp := self.fp
+        pc := fp.pc
+
+        /* check for program counter */
+        if pc >= len(fp.code) {
+            panic("fatal: program is not returned properly: \n" + fp.code.String())
+        }
+
+        /* fetch the next instruction */
+        iv := fp.code[pc]
+        op := iv.Op()
+
+        /* main switch on opcode */
+        switch fp.pc++; op {
+            default: {
+                panic(MakeError(ErrRuntime, "eval: invalid instruction: " + iv.String()))
+            }
+
+            /* load constant into stack */
+            case OP_ldconst: {
+                if iv.Iv() == 0 {
+                    fp.st = append(fp.st, iv.Rv())
+                } else {
+                    self.folded(fp, pc, iv)
+                }
+            }
+
+            /* load proc into stack */
+            case OP_ldproc: {
+                fp.st = append(fp.st, iv.Fn().load(fp.env, fp.vars, fp.links.proc(pc, iv.Fn())))
+            }
+
+            /* load variable into stack */
+            case OP_ldvar: {
+                fp.st = append(fp.st, fp.link(pc).load())
+            }
+
+            /* load local variable into stack */
+            case OP_ldlocal: {
+                depth, index := iv.Av()
+                fp.st = append(fp.st, fp.vars.Lookup(depth).vals[index])
+            }
+
+            /* store the stack top into local variable */
+            case OP_stlocal: {
+                depth, index := iv.Av()
+                fp.vars.Lookup(depth).vals[index] = sttop(fp.st)
+            }
+
+            /* define a new variable */
+            case OP_define: {
+                fp.link(pc).define(sttop(fp.st))
+            }
+
+            /* set new value to an existing variable */
+            case OP_set: {
+                fp.link(pc).store(sttop(fp.st))
+            }
+
+            /* get the first half of a pair */
+            case OP_car: {
+                if r, ok := sttop(fp.st).(*List); ok {
+                    stsub(fp.st, r.Car)
+                } else {
+                    panic(MakeError(ErrType, "eval: invalid argument type for car", sttop(fp.st)))
+                }
+            }
+
+            /* get the second half of a pair */
+            case OP_cdr: {
+                if r, ok := sttop(fp.st).(*List); ok {
+                    stsub(fp.st, r.Cdr)
+                } else {
+                    panic(MakeError(ErrType, "eval: invalid argument type for cdr", sttop(fp.st)))
+                }
+            }
+
+            /* construct a new pair from stack */
+            case OP_cons: {
+                cdr := stpop(&fp.st)
+                car := sttop(fp.st)
+                stsub(fp.st, MakePair(car, cdr))
+            }
+
+            /* drop the stack top */
+            case OP_drop: {
+                stpop(&fp.st)
+            }
+
+            /* unconditional jump */
+            case OP_goto: {
+                if fp.pc = int(iv.Iv()); fp.pc < 0 || fp.pc >= len(fp.code) {
+                    panic("fatal: branch out of scope: " + iv.String())
+                }
+            }
+
+            /* branch if the stack top is #f */
+            case OP_if_false: {
+                if !istrue(stpop(&fp.st)) {
+                    if fp.pc = int(iv.Iv()); fp.pc < 0 || fp.pc >= len(fp.code) {
+                        panic("fatal: branch out of scope: " + iv.String())
+                    }
+                }
+            }
+
+            /* assert the stack top is true, otherwise branch */
+            case OP_assert_true: {
+                if istrue(sttop(fp.st)) {
+                    stpop(&fp.st)
+                } else if fp.pc = int(iv.Iv()); fp.pc < 0 || fp.pc >= len(fp.code) {
+                    panic("fatal: branch out of scope: " + iv.String())
+                }
+            }
+
+            /* assert the stack top is false, otherwise branch */
+            case OP_assert_false: {
+                if !istrue(sttop(fp.st)) {
+                    stpop(&fp.st)
+                } else if fp.pc = int(iv.Iv()); fp.pc < 0 || fp.pc >= len(fp.code) {
+                    panic("fatal: branch out of scope: " + iv.String())
+                }
+            }
+
+            /* apply subroutine, maybe tail-call */
+            case OP_apply, OP_tailcall: {
+                vv := strem(&fp.st, int(iv.Iv()))
+                self.apply(vv[0], vv[1:], op == OP_tailcall)
+            }
+
+            /* binary arithmetic and comparison */
+            case OP_add, OP_sub, OP_mul, OP_div, OP_eq, OP_lt, OP_gt, OP_le, OP_ge: {
+                self.primitive(fp, pc)
+            }
+
+            /* return from subroutine */
+            case OP_return: {
+                if len(fp.st) != 1 {
+                    panic("fatal: unbalanced stack")
+                } else {
+                    self.fp = fp.prev
+                    self.ret(fp.st[0])
+                    self.release(fp)
+                }
+            }
+        }
+    }
+}
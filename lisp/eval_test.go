@@ -0,0 +1,227 @@
+package lisp
+
+import (
+    `testing`
+
+    `github.com/stretchr/testify/require`
+)
+
+func TestEval_Expression(t *testing.T) {
+    src := `
+        (letrec ((fac (λ (v r)
+                         (if (= v 0) r (fac (- v 1) (* v r))))))
+                (display (fac 10 1))
+                (newline))
+    `
+    prog := Compiler{}.Compile(CreateParser(src).Parse())
+    println(prog.String())
+    println(AsString(Evaluate(CreateGlobalScope(), prog)))
+}
+
+func evalsrc(src string) Value {
+    return Evaluate(CreateGlobalScope(), Compiler{}.Compile(CreateParser(src).Parse()))
+}
+
+func TestEval_Quasiquote(t *testing.T) {
+    require.Equal(t, "(a 5 1 2 3 b)", AsString(evalsrc("(define x 5) (define r (list 1 2 3)) `(a ,x ,@r b)")))
+    require.Equal(t, "(1 (quasiquote (2 (unquote (3 5)))))", AsString(evalsrc("(define x 5) `(1 `(2 ,(3 ,x)))")))
+    require.Equal(t, "(a . 5)", AsString(evalsrc("(define x 5) `(a . ,x)")))
+}
+
+func TestEval_Vector(t *testing.T) {
+    require.Equal(t, "#(1 a #(2))", AsString(evalsrc("(define v (make-vector 3 #(2))) (vector-set! v 0 1) (vector-set! v 1 'a) v")))
+    require.Equal(t, "#(1 7 2 3)", AsString(evalsrc("(define x 7) `#(1 ,x ,@(list 2 3))")))
+    require.Equal(t, "#(11 22)", AsString(evalsrc("(vector-map + #(1 2 3) #(10 20))")))
+    require.Equal(t, "(2 3)", AsString(evalsrc("(vector->list #(1 2 3 4) 1 3)")))
+    require.Equal(t, "6", AsString(evalsrc("(vector-fold (lambda (s x) (+ s x)) 0 #(1 2 3))")))
+    require.PanicsWithError(t, "make-vector: vector size out of range: 1000000000000", func() { evalsrc("(make-vector 1000000000000)") })
+}
+
+func TestEval_Macro(t *testing.T) {
+    swap := "(define-syntax swap! (syntax-rules () ((_ a b) (let ((tmp a)) (set! a b) (set! b tmp)))))"
+    myor := "(define-syntax my-or (syntax-rules () ((_) #f) ((_ e) e) ((_ e r ...) (let ((t e)) (if t t (my-or r ...))))))"
+    require.Equal(t, "(2 1)", AsString(evalsrc(swap + "(define tmp 1) (define y 2) (swap! tmp y) (list tmp y)")))
+    require.Equal(t, "5", AsString(evalsrc(myor + "(define t 5) (my-or #f t)")))
+    require.Equal(t, "7", AsString(evalsrc(myor + "(let ((if list)) (my-or #f 7))")))
+    require.Equal(t, "(1 2 3 4 5)", AsString(evalsrc("(define-syntax flat (syntax-rules () ((_ (a ...) ...) '(a ... ...)))) (flat (1 2) (3) (4 5))")))
+    require.Equal(t, "42", AsString(evalsrc("(let-syntax ((foo (syntax-rules () ((_ x) (* x 2))))) (foo 21))")))
+}
+
+func TestEval_RestArgs(t *testing.T) {
+    require.Equal(t, "(1 (2 3))", AsString(evalsrc("(define (f x . more) (list x more)) (f 1 2 3)")))
+    require.Equal(t, "(1 ())", AsString(evalsrc("(define (f x . more) (list x more)) (f 1)")))
+    require.Equal(t, "(1 2)", AsString(evalsrc("((lambda args args) 1 2)")))
+    require.Equal(t, "(1 2 (3 4))", AsString(evalsrc("((lambda (a b . c) (list a b c)) 1 2 3 4)")))
+    require.Equal(t, "#[proc (f x . more)]", AsString(evalsrc("(define (f x . more) x) f")))
+    require.PanicsWithError(t, "eval: proc f requires at least 1 argument, got 0", func() { evalsrc("(define (f x . more) x) (f)") })
+    require.PanicsWithError(t, "eval: proc f requires at least 2 arguments, got 1", func() { evalsrc("(define (f x y . more) x) (f 1)") })
+    require.PanicsWithError(t, "eval: proc f takes exact 1 argument, got 2", func() { evalsrc("(define (f x) x) (f 1 2)") })
+    require.PanicsWithError(t, "eval: proc f takes no arguments, got 1", func() { evalsrc("(define (f) 1) (f 1)") })
+}
+
+func TestEval_Error(t *testing.T) {
+    err := CatchError(func() { evalsrc("(vector-ref #(1 2) 'a)") })
+    require.IsType(t, (*LispError)(nil), err)
+    require.Equal(t, ErrType, err.(*LispError).Kind)
+    require.Equal(t, []Value{Atom("a")}, err.(*LispError).Irritants)
+    require.Equal(t, "vector-ref: object is not an integer: a", err.Error())
+    require.Equal(t, ErrUnbound, CatchError(func() { evalsrc("(undefined-proc 1)") }).(*LispError).Kind)
+    require.Equal(t, ErrArity, CatchError(func() { evalsrc("((lambda (x) x))") }).(*LispError).Kind)
+    require.Equal(t, `("vector-ref: index out of range" (5))`, AsString(evalsrc(`
+        (with-error-handler
+            (lambda (e) (list (error-object-message e) (error-object-irritants e)))
+            (lambda () (vector-ref #(1 2) 5)))
+    `)))
+}
+
+func TestEval_TailCall(t *testing.T) {
+    require.Equal(t, "global", AsString(evalsrc("(define x 'global) (define (g) x) (define (f x) (g)) (f 'local)")))
+    require.Equal(t, "#t", AsString(evalsrc("(define (ev? n) (if (= n 0) #t (od? (- n 1)))) (define (od? n) (if (= n 0) #f (ev? (- n 1)))) (ev? 100000)")))
+    require.Equal(t, "done", AsString(evalsrc("(define (loop n) (if (= n 0) 'done (apply loop (list (- n 1))))) (loop 100000)")))
+    require.Equal(t, "(1 2 3 4)", AsString(evalsrc("(apply list 1 2 '(3 4))")))
+    require.Equal(t, "6", AsString(evalsrc("(vector-fold (lambda (s x) (apply + s (list x))) 0 #(1 2 3))")))
+}
+
+func TestEval_CallCC(t *testing.T) {
+    require.Equal(t, "2", AsString(evalsrc("(+ 1 (call/cc (lambda (k) (+ 10 (k 1)))))")))
+    require.Equal(t, "#(1 20 3)", AsString(evalsrc("(vector-map (lambda (x) (call/cc (lambda (k) (if (= x 2) (k 20) x)))) #(1 2 3))")))
+    require.Equal(t, "found", AsString(evalsrc("(call/cc (lambda (out) (vector-for-each (lambda (x) (if (= x 2) (out 'found))) #(1 2 3)) 'none))")))
+    require.Equal(t, "(3 2 1 0)", AsString(evalsrc(`
+        (define acc '())
+        (define (g)
+            (define k #f)
+            (define v (call/cc (lambda (c) (set! k c) 0)))
+            (set! acc (cons v acc))
+            (if (< v 3) (k (+ v 1)) acc))
+        (g)
+    `)))
+    require.PanicsWithError(t, "continuation: cannot re-enter a nested call that has already returned", func() {
+        evalsrc(`
+            (define k #f)
+            (define n 0)
+            (let ()
+                (display (vector-map (lambda (x) (call/cc (lambda (c) (if (= x 2) (set! k c)) x))) #(1 2)))
+                (newline)
+                (set! n (+ n 1))
+                (if (< n 3) (k 10))
+                (display "end"))
+        `)
+    })
+    require.PanicsWithError(t, "continuation: cannot re-enter a nested call that has already returned", func() {
+        evalsrc(`
+            (define k #f)
+            (define n 0)
+            (vector-for-each (lambda (x) (call/cc (lambda (c) (set! k c)))) #(1))
+            (set! n (+ n 1))
+            (if (< n 3) (k #f))
+        `)
+    })
+}
+
+func TestEval_DynamicWind(t *testing.T) {
+    require.Equal(t, "(esc (in body out in body out))", AsString(evalsrc(`
+        (define trace '())
+        (define (note x) (set! trace (cons x trace)))
+        (define (rev l a) (if l (rev (cdr l) (cons (car l) a)) a))
+        (define (run)
+            (define k #f)
+            (define n 0)
+            (dynamic-wind
+                (lambda () (note 'in))
+                (lambda () (call/cc (lambda (c) (set! k c))) (note 'body))
+                (lambda () (note 'out)))
+            (set! n (+ n 1))
+            (if (< n 2) (k 'again))
+            (list (call/cc (lambda (k) (dynamic-wind (lambda () #f) (lambda () (k 'esc)) (lambda () #f))))
+                  (rev trace '())))
+        (run)
+    `)))
+}
+
+func TestEval_DeepRecursion(t *testing.T) {
+    src := `
+        (define (iota n acc) (if (= n 0) acc (iota (- n 1) (cons n acc))))
+        (define (len l) (if l (+ 1 (len (cdr l))) 0))
+        (len (iota 200000 '()))
+    `
+    require.Equal(t, "200000", AsString(evalsrc(src)))
+    require.PanicsWithError(t, "eval: stack overflow", func() { evalsrc("(define (f n) (+ 1 (f n))) (f 1)") })
+    require.PanicsWithError(t, "eval: stack overflow", func() { evalsrc("(define (g v) (vector-map g (vector v))) (g 1)") })
+}
+
+func TestEval_Exception(t *testing.T) {
+    require.Equal(t, "(caught boom)", AsString(evalsrc("(guard (e (#t (list 'caught e))) (raise 'boom))")))
+    require.Equal(t, "(else 7)", AsString(evalsrc("(guard (e ((error-object? e) 'err) (else (list 'else e))) (raise 7))")))
+    require.Equal(t, "\"modulo: division by zero\"", AsString(evalsrc("(guard (e ((error-object? e) (error-object-message e))) (modulo 1 0))")))
+    require.Equal(t, "(\"bad\" (1 2))", AsString(evalsrc("(guard (e (#t (list (error-object-message e) (error-object-irritants e)))) (error \"bad\" 1 2))")))
+    require.Equal(t, "42", AsString(evalsrc("(with-exception-handler (lambda (e) 41) (lambda () (+ 1 (raise-continuable 'oops))))")))
+    require.Equal(t, "(outer inner)", AsString(evalsrc("(guard (e (#t (list 'outer e))) (guard (e ((error-object? e) 'err)) (raise 'inner)))")))
+    require.Equal(t, "42", AsString(evalsrc("(guard (e ((car e) => (lambda (x) (* x 2)))) (raise (list 21)))")))
+    require.Equal(t, "\"eval: invalid argument type for car\"", AsString(evalsrc("(guard (e (#t (error-object-message e))) (vector-map (lambda (x) (car x)) #(1)))")))
+    require.Equal(t, "(1 2 w)", AsString(evalsrc("(define r '()) (define v (guard (e (#t e)) (dynamic-wind (lambda () (set! r (cons 1 r))) (lambda () (raise 'w)) (lambda () (set! r (cons 2 r)))))) (append (list (car (cdr r)) (car r)) (list v))")))
+    require.PanicsWithError(t, "uncaught exception: oops", func() { evalsrc("(raise 'oops)") })
+    require.PanicsWithError(t, "raise: handler returned from non-continuable exception: again", func() { evalsrc("(with-exception-handler (lambda (e) 0) (lambda () (raise 'again)))") })
+}
+
+func TestEval_LexicalScope(t *testing.T) {
+    require.Equal(t, "(1 2 3)", AsString(evalsrc("(define (counter) (define n 0) (lambda () (set! n (+ n 1)) n)) (define c (counter)) (list (c) (c) (c))")))
+    require.Equal(t, "(10 20)", AsString(evalsrc("(define (f x) (let-syntax ((get (syntax-rules () ((_) x)))) (let ((x 20)) (list (get) x)))) (f 10)")))
+    require.Equal(t, "(1 (2 3))", AsString(evalsrc("(define (f a . r) (define (g) (list a r)) (g)) (f 1 2 3)")))
+    require.Equal(t, "6", AsString(evalsrc("(do ((i 0 (+ i 1)) (s 0 (+ s i))) ((> i 3) s) #t)")))
+}
+
+func TestEval_GlobalCell(t *testing.T) {
+    require.Equal(t, "(1 2 3)", AsString(evalsrc("(define x 1) (define (f) x) (define a (f)) (set! x 2) (define b (f)) (define x 3) (list a b (f))")))
+    require.PanicsWithError(t, "eval: undefined reference: y", func() { evalsrc("(set! y 1)") })
+
+    /* the same program linked against different scopes */
+    s1 := CreateGlobalScope()
+    s2 := CreateGlobalScope()
+    s1.Set("v", Int(1))
+    s2.Set("v", Int(2))
+    prog := Compiler{}.Compile(CreateParser("v").Parse())
+    require.Equal(t, Int(1), Evaluate(s1, prog))
+    require.Equal(t, Int(2), Evaluate(s2, prog))
+    require.Equal(t, Int(1), Evaluate(s1, prog))
+
+    /* linking must not modify the compiled code, which is shared between scopes */
+    prog = Compiler{}.Compile(CreateParser("(begin (define (f) (+ v 1)) (f))").Parse())
+    code := append(Program(nil), prog...)
+    body := append(Program(nil), prog[0].Fn().Code...)
+    require.Equal(t, Int(2), Evaluate(s1, prog))
+    require.Equal(t, Int(3), Evaluate(s2, prog))
+    require.Equal(t, code, prog)
+    require.Equal(t, body, prog[0].Fn().Code)
+}
+
+func TestEval_Primitive(t *testing.T) {
+    require.Equal(t, "(3 1.5 -1 #t #f)", AsString(evalsrc("(list (+ 1 2) (* 0.5 3.0) (- 1 2) (< 1 2) (>= 1.0 2))")))
+    require.Equal(t, "9223372036854775808", AsString(evalsrc("(+ 9223372036854775807 1)")))
+    require.Equal(t, "1/2", AsString(evalsrc("(/ 1 2)")))
+    require.Equal(t, "(-1 3)", AsString(evalsrc("(define (f) (+ 1 2)) (define a (begin (set! + -) (f))) (define + (lambda (a b) 3)) (list a (f))")))
+}
+
+func TestEval_Disassemble(t *testing.T) {
+    require.PanicsWithError(t, "disassemble: object is not a compiled proc: 1", func() { evalsrc("(disassemble 1)") })
+    require.PanicsWithError(t, "disassemble: proc requires 1 or 2 arguments", func() { evalsrc("(disassemble)") })
+}
+
+func BenchmarkEval_Call(b *testing.B) {
+    it := CreateInterpreter()
+    fn, err := it.Eval("(define (fib n) (if (< n 2) n (+ (fib (- n 1)) (fib (- n 2))))) fib")
+    require.NoError(b, err)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, _ = it.Call(fn, Int(20))
+    }
+}
+
+func BenchmarkEval_Loop(b *testing.B) {
+    it := CreateInterpreter()
+    fn, err := it.Eval("(define (loop n) (define (iter i s) (if (< i n) (iter (+ i 1) (+ s (* i 0.5))) s)) (iter 0 0.0)) loop")
+    require.NoError(b, err)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, _ = it.Call(fn, Int(10000))
+    }
+}
@@ -0,0 +1,10 @@
+package lisp
+
+type IdGen struct {
+    incr uint32
+}
+
+func (self *IdGen) Next() int {
+    self.incr++
+    return int(self.incr)
+}
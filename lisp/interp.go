@@ -0,0 +1,227 @@
+package lisp
+
+import (
+    `fmt`
+    `io`
+    `os`
+    `strings`
+)
+
+const (
+    MaxFreeFrames        = 256
+    DefaultMaxStackDepth = 1000000
+    DefaultMaxNestedCall = 10000
+)
+
+type Interpreter struct {
+    Stdout        *Port
+    CommandLine   []string
+    MaxStackDepth int
+    MaxNestedCall int
+    NoOptimize    bool
+    ids           IdGen
+    rebound       uint32
+    nfree         int
+    frames        *_Frame
+    env           *Environ
+    scope         *Scope
+    active        *Machine
+    winds         *_Winder
+    handlers      *_Handler
+    controls      map[string]*Control
+    intrinsics    map[string]*Intrinsic
+}
+
+func CreateInterpreter() *Interpreter {
+    ret := &Interpreter {
+        Stdout        : CreatePort("<stdout>", os.Stdout),
+        MaxStackDepth : DefaultMaxStackDepth,
+        MaxNestedCall : DefaultMaxNestedCall,
+        controls      : make(map[string]*Control, len(controlTab)),
+        intrinsics    : make(map[string]*Intrinsic, len(intrinsicsTab) + len(intrinsicsBoundTab)),
+    }
+
+    /* intrinsics without any states are shared between interpreters */
+    for k, v := range intrinsicsTab {
+        ret.intrinsics[k] = v
+    }
+
+    /* the others are bound to this interpreter */
+    for k, v := range intrinsicsBoundTab {
+        ret.intrinsics[k] = ret.bind(k, v)
+    }
+
+    /* control procs may also be called from Go code, which requires the interpreter */
+    for k, v := range controlTab {
+        ret.controls[k] = &Control { Name: v.Name, Proc: v.Proc, it: ret }
+    }
+
+    /* create the global environment and scope */
+    ret.env = createEnviron(&ret.ids)
+    ret.scope = &Scope { it: ret, defs: make(map[string]*Cell, 16) }
+
+    /* initialize the global scope */
+    ret.scope.initAsGlobal()
+    return ret
+}
+
+func (self *Interpreter) bind(name string, proc func(*Interpreter, []Value) Value) *Intrinsic {
+    return newIntrinsic(name, func(args []Value) Value {
+        return proc(self, args)
+    })
+}
+
+func (self *Interpreter) protect(fn func()) error {
+    top := self.active == nil
+    err := CatchError(fn)
+
+    /* errors escaped from the top level leave the dynamic states dangling */
+    if err != nil && top {
+        self.reset()
+    }
+
+    /* all done */
+    return err
+}
+
+func (self *Interpreter) reset() {
+    self.handlers = nil
+
+    /* leave all the dynamic extents that the error escaped from */
+    if CatchError(func() { self.rewind(nil) }) != nil {
+        self.winds = nil
+    }
+}
+
+func (self *Interpreter) compiler(sm SourceMap) Compiler {
+    return Compiler {
+        Spans      : sm,
+        Global     : self.env,
+        NoOptimize : self.NoOptimize,
+    }
+}
+
+func (self *Interpreter) load(ps *Parser) (ret Value) {
+    /* compile and evaluate every top-level datum as soon as it is read, the spans are dropped along with the datum */
+    for vv, ok := ps.Next(); ok; vv, ok = ps.Next() {
+        ret = self.evaluate(self.compiler(ps.Spans()).Compile(MakeList(Atom("begin"), vv)))
+    }
+
+    /* all done */
+    return
+}
+
+func (self *Interpreter) evaluate(p Program) Value {
+    vm := self.newMachine()
+    fp := vm.frame()
+    fp.code = p
+    fp.env = self.scope
+    fp.links = newLinks(p)
+    vm.push(fp)
+    return vm.run()
+}
+
+func (self *Interpreter) apply(fn Value, args []Value) Value {
+    vm := self.newMachine()
+    vm.apply(fn, args, false)
+    return vm.run()
+}
+
+func (self *Interpreter) newMachine() *Machine {
+    vm := new(Machine)
+    vm.it = self
+    vm.up = self.active
+
+    /* nested machines continue counting from where the outer machine is */
+    if vm.up != nil {
+        vm.base = vm.up.depth() + 1
+        vm.nest = vm.up.nest + 1
+    }
+
+    /* every nested machine consumes some of the native stack */
+    if vm.nest > self.MaxNestedCall {
+        panic(MakeError(ErrRuntime, "eval: stack overflow"))
+    }
+
+    /* all done */
+    return vm
+}
+
+/** Interpreter Interface **/
+
+func (self *Interpreter) Eval(src string) (ret Value, err error) {
+    if err = self.protect(func() { ret = self.load(CreateParser(src)) }); err != nil {
+        ret = nil
+    }
+    return
+}
+
+func (self *Interpreter) Load(path string) error {
+    var err error
+    var rfp *os.File
+    var ret []Program
+
+    /* compiled bytecode files are evaluated directly */
+    if strings.HasSuffix(path, BytecodeExt) {
+        if ret, err = ReadBytecodeFile(path); err != nil {
+            return err
+        } else {
+            return self.protect(func() { for _, p := range ret { self.evaluate(p) } })
+        }
+    }
+
+    /* open the file */
+    if rfp, err = os.OpenFile(path, os.O_RDONLY, 0); err != nil {
+        return MakeError(ErrIO, fmt.Sprintf("io: unable to open %s: %s", path, err))
+    }
+
+    /* load the file, and close it after loading */
+    defer rfp.Close()
+    return self.LoadFrom(path, rfp)
+}
+
+func (self *Interpreter) LoadFrom(name string, rd io.Reader) error {
+    return self.protect(func() {
+        self.load(CreateStreamParser(name, rd))
+    })
+}
+
+func (self *Interpreter) Compile(name string, rd io.Reader) (ret []Program, err error) {
+    err = CatchError(func() {
+        ps := CreateStreamParser(name, rd)
+
+        /* compile every top-level datum in order, macros are shared between them */
+        for vv, ok := ps.Next(); ok; vv, ok = ps.Next() {
+            ret = append(ret, self.compiler(ps.Spans()).Compile(MakeList(Atom("begin"), vv)))
+        }
+    })
+    return
+}
+
+func (self *Interpreter) Execute(p Program) (ret Value, err error) {
+    if err = self.protect(func() { ret = self.evaluate(p) }); err != nil {
+        ret = nil
+    }
+    return
+}
+
+func (self *Interpreter) Call(proc Value, args ...Value) (ret Value, err error) {
+    if err = self.protect(func() { ret = self.apply(proc, args) }); err != nil {
+        ret = nil
+    }
+    return
+}
+
+func (self *Interpreter) Define(name string, value Value) {
+    self.scope.Set(name, value)
+}
+
+func (self *Interpreter) Lookup(name string) (Value, bool) {
+    return self.scope.Get(name)
+}
+
+func (self *Interpreter) RegisterIntrinsic(name string, proc func([]Value) Value) {
+    fn := newIntrinsic(name, proc)
+    self.intrinsics[name] = fn
+    self.Define(name, fn)
+}
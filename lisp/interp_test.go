@@ -0,0 +1,153 @@
+package lisp
+
+import (
+    `errors`
+    `sync`
+    `testing`
+
+    `github.com/stretchr/testify/require`
+)
+
+func TestInterpreter_Eval(t *testing.T) {
+    it := CreateInterpreter()
+    v, err := it.Eval("(define (sq x) (* x x)) (sq 12)")
+    require.NoError(t, err)
+    require.Equal(t, Int(144), v)
+    v, err = it.Eval("(car 1)")
+    require.Error(t, err)
+    require.Nil(t, v)
+    v, err = it.Eval("(sq 3)")
+    require.NoError(t, err)
+    require.Equal(t, Int(9), v)
+}
+
+func TestInterpreter_Isolation(t *testing.T) {
+    i1 := CreateInterpreter()
+    i2 := CreateInterpreter()
+    i1.Define("x", Int(1))
+    _, err := i2.Eval("x")
+    require.Error(t, err)
+    _, ok := i2.Lookup("x")
+    require.False(t, ok)
+    v, ok := i1.Lookup("x")
+    require.True(t, ok)
+    require.Equal(t, Int(1), v)
+}
+
+func TestInterpreter_Call(t *testing.T) {
+    it := CreateInterpreter()
+    it.RegisterIntrinsic("twice", func(args []Value) Value { return MakeList(args[0], args[0]) })
+    fn, err := it.Eval("(lambda (a b) (twice (+ a b)))")
+    require.NoError(t, err)
+    v, err := it.Call(fn, Int(1), Int(2))
+    require.NoError(t, err)
+    require.Equal(t, "(3 3)", AsString(v))
+    _, err = it.Call(fn, Int(1))
+    require.Error(t, err)
+    _, err = it.Call(Int(1))
+    require.Error(t, err)
+}
+
+func TestInterpreter_Continuation(t *testing.T) {
+    it := CreateInterpreter()
+    it.RegisterIntrinsic("invoke", func(args []Value) Value { return Apply(args[0], nil) })
+    fn, err := it.Eval("(define k #f) (lambda () (+ 1 (call/cc (lambda (c) (set! k c) 1))))")
+    require.NoError(t, err)
+    v, err := it.Call(fn)
+    require.NoError(t, err)
+    require.Equal(t, Int(2), v)
+    v, err = it.Eval("(k 10)")
+    require.NoError(t, err)
+    require.Equal(t, Int(11), v)
+    v, err = it.Eval("(invoke (lambda () (call/cc (lambda (c) (set! k c) 1))))")
+    require.NoError(t, err)
+    require.Equal(t, Int(1), v)
+    _, err = it.Eval("(k 10)")
+    require.EqualError(t, err, "continuation: cannot re-enter a nested call that has already returned")
+}
+
+func TestInterpreter_Recover(t *testing.T) {
+    it := CreateInterpreter()
+    _, err := it.Eval(`
+        (define n 0)
+        (dynamic-wind (lambda () #t) (lambda () (car 1)) (lambda () (set! n (+ n 1))))
+    `)
+    require.Error(t, err)
+    require.Nil(t, it.winds)
+    require.Nil(t, it.handlers)
+    require.Nil(t, it.active)
+    v, _ := it.Lookup("n")
+    require.Equal(t, Int(1), v)
+}
+
+func TestInterpreter_Errors(t *testing.T) {
+    it := CreateInterpreter()
+    it.RegisterIntrinsic("fail", func([]Value) Value { panic(errors.New("broken")) })
+    it.RegisterIntrinsic("crash", func([]Value) Value { var v *Vector; return v.Elems[0] })
+    _, err := it.Eval("(define x 1)\n(fail)")
+    require.EqualError(t, err, "line 2: broken")
+    v, err := it.Eval("(with-error-handler (lambda (e) (error-object-message e)) (lambda () (fail)))")
+    require.NoError(t, err)
+    require.Equal(t, String("broken"), v)
+    require.Panics(t, func() { _, _ = it.Eval("(crash)") })
+    require.Panics(t, func() { _, _ = it.Eval("(with-error-handler (lambda (e) 0) (lambda () (crash)))") })
+}
+
+func TestInterpreter_Aliases(t *testing.T) {
+    it := CreateInterpreter()
+    for i := 0; i < 100; i++ {
+        v, err := it.Eval("(guard (e (#t 0)) 1)")
+        require.NoError(t, err)
+        require.Equal(t, Int(1), v)
+    }
+    require.Empty(t, it.env.refs)
+    _, err := it.Eval(`
+        (define-syntax define-getter
+          (syntax-rules ()
+            ((_ name val) (define-syntax name (syntax-rules () ((_) (let ((tmp val)) (list tmp 'tmp))))))))
+        (define-getter get 42)
+        (define tmp 1)
+    `)
+    require.NoError(t, err)
+    v, err := it.Eval("(get)")
+    require.NoError(t, err)
+    require.Equal(t, "(42 tmp)", AsString(v))
+}
+
+func TestInterpreter_CommandLine(t *testing.T) {
+    it := CreateInterpreter()
+    it.CommandLine = []string { "a.scm", "x" }
+    v, err := it.Eval("(command-line)")
+    require.NoError(t, err)
+    require.Equal(t, `("a.scm" "x")`, AsString(v))
+}
+
+func TestInterpreter_Concurrent(t *testing.T) {
+    var wg sync.WaitGroup
+    var ret [4]Value
+    var err [4]error
+
+    /* independent interpreters must be able to run on separated goroutines */
+    for i := range ret {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            it := CreateInterpreter()
+            it.Define("n", Int(i))
+            ret[i], err[i] = it.Eval(`
+                (define-syntax twice (syntax-rules () ((_ x) (* 2 x))))
+                (define r 0)
+                (define k #f)
+                (define (f x) (dynamic-wind (lambda () (set! r (+ r 1))) (lambda () (twice x)) (lambda () #t)))
+                (list (f n) (call/cc (lambda (c) (set! k c) n)) r)
+            `)
+        }(i)
+    }
+
+    /* check for the results */
+    wg.Wait()
+    for i := range ret {
+        require.NoError(t, err[i])
+        require.Equal(t, MakeList(Int(i * 2), Int(i), Int(1)), ret[i])
+    }
+}
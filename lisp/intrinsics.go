@@ -0,0 +1,943 @@
+package lisp
+
+import (
+    `fmt`
+)
+
+const (
+    MaxVectorSize = 1 << 28
+)
+
+type Intrinsic struct {
+    Name string
+    Proc func([]Value) Value
+}
+
+var (
+    intrinsicsTab      = make(map[string]*Intrinsic)
+    intrinsicsBoundTab = make(map[string]func(*Interpreter, []Value) Value)
+)
+
+func newIntrinsic(name string, proc func([]Value) Value) *Intrinsic {
+    return &Intrinsic {
+        Name: name,
+        Proc: proc,
+    }
+}
+
+func isIntrinsic(name string) bool {
+    _, p := intrinsicsTab[name]
+    _, q := intrinsicsBoundTab[name]
+    return p || q
+}
+
+func RegisterIntrinsic(name string, proc func([]Value) Value) {
+    if isIntrinsic(name) {
+        panic("registry: duplicated intrinsic proc: " + name)
+    } else {
+        intrinsicsTab[name] = newIntrinsic(name, proc)
+    }
+}
+
+func RegisterBoundIntrinsic(name string, proc func(*Interpreter, []Value) Value) {
+    if isIntrinsic(name) {
+        panic("registry: duplicated intrinsic proc: " + name)
+    } else {
+        intrinsicsBoundTab[name] = proc
+    }
+}
+
+func (self *Intrinsic) Call(args []Value) Value {
+    ret := self.Proc(args)
+    tc, ok := ret.(*TailCall)
+
+    /* resolve the tail-call if requested */
+    if !ok {
+        return ret
+    } else {
+        return tc.Resolve()
+    }
+}
+
+func (self *Intrinsic) String() string {
+    return fmt.Sprintf("#[intrinsic-%s]", self.Name)
+}
+
+func (self *Intrinsic) IsIdentity() bool {
+    return true
+}
+
+/** Arithmetic Operators **/
+
+func intrinsicsAdd(args []Value) Value {
+    switch len(args) {
+        case 0  : return Int(0)
+        case 1  : return AsNumber(args[0])
+        case 2  : return NumberAdd(args[0], args[1])
+        default : return reduceSequential(args, NumberAdd)
+    }
+}
+
+func intrinsicsSub(args []Value) Value {
+    switch len(args) {
+        case 0  : panic(MakeError(ErrArity, "-: proc requies at least 1 argument"))
+        case 1  : return NumberNeg(args[0])
+        case 2  : return NumberSub(args[0], args[1])
+        default : return reduceSequential(args, NumberSub)
+    }
+}
+
+func intrinsicsMul(args []Value) Value {
+    switch len(args) {
+        case 0  : return Int(1)
+        case 1  : return AsNumber(args[0])
+        case 2  : return NumberMul(args[0], args[1])
+        default : return reduceSequential(args, NumberMul)
+    }
+}
+
+func intrinsicsDiv(args []Value) Value {
+    switch len(args) {
+        case 0  : panic(MakeError(ErrArity, "/: proc requies at least 1 argument"))
+        case 1  : return NumberInv(args[0])
+        case 2  : return NumberDiv(args[0], args[1])
+        default : return reduceSequential(args, NumberDiv)
+    }
+}
+
+func init() {
+    RegisterIntrinsic("+", intrinsicsAdd)
+    RegisterIntrinsic("-", intrinsicsSub)
+    RegisterIntrinsic("*", intrinsicsMul)
+    RegisterIntrinsic("/", intrinsicsDiv)
+}
+
+/** Comparison Operators **/
+
+func intrinsicsEq(args []Value) Value {
+    switch len(args) {
+        case 0  : fallthrough
+        case 1  : return Bool(true)
+        case 2  : return Bool(NumberCompareEq(args[0], args[1]))
+        default : return Bool(reduceConvolution(args, NumberCompareEq))
+    }
+}
+
+func intrinsicsLt(args []Value) Value {
+    switch len(args) {
+        case 0  : fallthrough
+        case 1  : return Bool(true)
+        case 2  : return Bool(NumberCompareLt(args[0], args[1]))
+        default : return Bool(reduceConvolution(args, NumberCompareLt))
+    }
+}
+
+func intrinsicsGt(args []Value) Value {
+    switch len(args) {
+        case 0  : fallthrough
+        case 1  : return Bool(true)
+        case 2  : return Bool(NumberCompareGt(args[0], args[1]))
+        default : return Bool(reduceConvolution(args, NumberCompareGt))
+    }
+}
+
+func intrinsicsLte(args []Value) Value {
+    switch len(args) {
+        case 0  : fallthrough
+        case 1  : return Bool(true)
+        case 2  : return Bool(NumberCompareLte(args[0], args[1]))
+        default : return Bool(reduceConvolution(args, NumberCompareLte))
+    }
+}
+
+func intrinsicsGte(args []Value) Value {
+    switch len(args) {
+        case 0  : fallthrough
+        case 1  : return Bool(true)
+        case 2  : return Bool(NumberCompareGte(args[0], args[1]))
+        default : return Bool(reduceConvolution(args, NumberCompareGte))
+    }
+}
+
+func init() {
+    RegisterIntrinsic("=", intrinsicsEq)
+    RegisterIntrinsic("<", intrinsicsLt)
+    RegisterIntrinsic(">", intrinsicsGt)
+    RegisterIntrinsic("<=", intrinsicsLte)
+    RegisterIntrinsic(">=", intrinsicsGte)
+}
+
+/** Unary Arithmetic Functions **/
+
+func intrinsicsRound(args []Value) Value {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "round: proc takes exact 1 argument"))
+    } else {
+        return NumberRound(args[0])
+    }
+}
+
+func intrinsicsMagnitude(args []Value) Value {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "magnitude: proc takes exact 1 argument"))
+    } else {
+        return NumberMagnitude(args[0])
+    }
+}
+
+func intrinsicsExact(args []Value) Value {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "exact: proc takes exact 1 argument"))
+    } else {
+        return NumberExact(args[0])
+    }
+}
+
+func intrinsicsInexact(args []Value) Value {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "inexact: proc takes exact 1 argument"))
+    } else {
+        return NumberInexact(args[0])
+    }
+}
+
+func intrinsicsIsExact(args []Value) Value {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "exact?: proc takes exact 1 argument"))
+    } else {
+        return Bool(AsNumber(args[0]).Kind() < NumFloat)
+    }
+}
+
+func intrinsicsIsInexact(args []Value) Value {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "inexact?: proc takes exact 1 argument"))
+    } else {
+        return Bool(AsNumber(args[0]).Kind() >= NumFloat)
+    }
+}
+
+func intrinsicsNumerator(args []Value) Value {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "numerator: proc takes exact 1 argument"))
+    } else {
+        return NumberNumerator(args[0])
+    }
+}
+
+func intrinsicsDenominator(args []Value) Value {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "denominator: proc takes exact 1 argument"))
+    } else {
+        return NumberDenominator(args[0])
+    }
+}
+
+func init() {
+    RegisterIntrinsic("round", intrinsicsRound)
+    RegisterIntrinsic("magnitude", intrinsicsMagnitude)
+    RegisterIntrinsic("exact", intrinsicsExact)
+    RegisterIntrinsic("inexact", intrinsicsInexact)
+    RegisterIntrinsic("exact?", intrinsicsIsExact)
+    RegisterIntrinsic("inexact?", intrinsicsIsInexact)
+    RegisterIntrinsic("exact->inexact", intrinsicsInexact)
+    RegisterIntrinsic("inexact->exact", intrinsicsExact)
+    RegisterIntrinsic("numerator", intrinsicsNumerator)
+    RegisterIntrinsic("denominator", intrinsicsDenominator)
+}
+
+/** Binary Arithmetic Functions **/
+
+func intrinsicsModulo(args []Value) Value {
+    if len(args) != 2 {
+        panic(MakeError(ErrArity, "modulo: proc takes exact 2 arguments"))
+    } else {
+        return NumberModulo(args[0], args[1])
+    }
+}
+
+func intrinsicsQuotient(args []Value) Value {
+    if len(args) != 2 {
+        panic(MakeError(ErrArity, "quotient: proc takes exact 2 arguments"))
+    } else {
+        return NumberQuotient(args[0], args[1])
+    }
+}
+
+func intrinsicsRemainder(args []Value) Value {
+    if len(args) != 2 {
+        panic(MakeError(ErrArity, "remainder: proc takes exact 2 arguments"))
+    } else {
+        return NumberRemainder(args[0], args[1])
+    }
+}
+
+func init() {
+    RegisterIntrinsic("modulo", intrinsicsModulo)
+    RegisterIntrinsic("quotient", intrinsicsQuotient)
+    RegisterIntrinsic("remainder", intrinsicsRemainder)
+}
+
+/** Value Constructors **/
+
+func intrinsicMakeRectangular(args []Value) Value {
+    if len(args) != 2 {
+        panic(MakeError(ErrArity, "make-rectangular: proc takes exact 2 arguments"))
+    } else {
+        return Complex(complex(float64(AsNumber(args[0]).AsFloat()), float64(AsNumber(args[1]).AsFloat())))
+    }
+}
+
+func init() {
+    RegisterIntrinsic("make-rectangular", intrinsicMakeRectangular)
+}
+
+/** List Functions **/
+
+func intrinsicsList(args []Value) Value {
+    return MakeList(args...)
+}
+
+func intrinsicsAppend(args []Value) Value {
+    var p *List
+    var q *List
+
+    /* empty list */
+    if len(args) == 0 {
+        return nil
+    }
+
+    /* copy every list except the last one */
+    for _, v := range args[:len(args) - 1] {
+        vv, ok := AsList(v)
+
+        /* copy every element */
+        for ; ok && vv != nil; vv, ok = AsList(vv.Cdr) {
+            AppendValue(&p, &q, vv.Car)
+        }
+
+        /* must be a proper list */
+        if !ok {
+            panic(MakeError(ErrType, "append: object is not a proper list", v))
+        }
+    }
+
+    /* the last one is shared with the result */
+    if q == nil {
+        return args[len(args) - 1]
+    } else {
+        q.Cdr = args[len(args) - 1]
+        return p
+    }
+}
+
+func intrinsicsApply(args []Value) Value {
+    if len(args) < 2 {
+        panic(MakeError(ErrArity, "apply: proc requires at least 2 arguments"))
+    }
+
+    /* the last argument is a list of the remaining arguments */
+    argv := append([]Value(nil), args[1:len(args) - 1]...)
+    argv = append(argv, asSlice("apply", args[len(args) - 1])...)
+
+    /* apply the proc as a tail-call */
+    return MakeTailCall(args[0], argv)
+}
+
+func init() {
+    RegisterIntrinsic("list", intrinsicsList)
+    RegisterIntrinsic("append", intrinsicsAppend)
+    RegisterIntrinsic("apply", intrinsicsApply)
+}
+
+/** Vector Functions **/
+
+func asVector(name string, v Value) *Vector {
+    if vv, ok := v.(*Vector); !ok {
+        panic(MakeError(ErrType, name + ": object is not a vector", v))
+    } else {
+        return vv
+    }
+}
+
+func asIndex(name string, v Value, limit int) int {
+    if iv, ok := v.(Int); !ok {
+        panic(MakeError(ErrType, name + ": object is not an integer", v))
+    } else if iv < 0 || iv > Int(limit) {
+        panic(MakeError(ErrRuntime, name + ": index out of range", iv))
+    } else {
+        return int(iv)
+    }
+}
+
+func asCallable(name string, v Value) Callable {
+    if fn, ok := v.(Callable); !ok {
+        panic(MakeError(ErrType, name + ": object is not appliable", v))
+    } else {
+        return fn
+    }
+}
+
+func asSlice(name string, v Value) (ret []Value) {
+    vv, ok := AsList(v)
+
+    /* convert every element */
+    for ; ok && vv != nil; vv, ok = AsList(vv.Cdr) {
+        ret = append(ret, vv.Car)
+    }
+
+    /* must be a proper list */
+    if !ok {
+        panic(MakeError(ErrType, name + ": object is not a proper list", v))
+    } else {
+        return
+    }
+}
+
+func vectorRange(name string, vec *Vector, args []Value) (int, int) {
+    switch len(args) {
+        case 0  : return 0, len(vec.Elems)
+        case 1  : return asIndex(name, args[0], len(vec.Elems)), len(vec.Elems)
+        case 2  : break
+        default : panic(MakeError(ErrArity, name + ": too many arguments"))
+    }
+
+    /* both start and end are specified */
+    i := asIndex(name, args[0], len(vec.Elems))
+    j := asIndex(name, args[1], len(vec.Elems))
+
+    /* check for range */
+    if i > j {
+        panic(MakeError(ErrRuntime, fmt.Sprintf("%s: invalid range [%d, %d)", name, i, j)))
+    } else {
+        return i, j
+    }
+}
+
+func vectorsArgs(name string, args []Value) (Callable, []*Vector, int) {
+    if len(args) < 2 {
+        panic(MakeError(ErrArity, name + ": proc requires at least 2 arguments"))
+    }
+
+    /* extract the proc and vectors */
+    fn := asCallable(name, args[0])
+    vv := make([]*Vector, len(args) - 1)
+
+    /* convert every vector */
+    for i, v := range args[1:] {
+        vv[i] = asVector(name, v)
+    }
+
+    /* the iteration stops at the shortest vector */
+    nb := len(vv[0].Elems)
+    for _, v := range vv[1:] {
+        if len(v.Elems) < nb {
+            nb = len(v.Elems)
+        }
+    }
+
+    /* all done */
+    return fn, vv, nb
+}
+
+func vectorsCall(fn Callable, vv []*Vector, i int, pfx ...Value) Value {
+    for _, v := range vv { pfx = append(pfx, v.Elems[i]) }
+    return fn.Call(pfx)
+}
+
+func intrinsicsMakeVector(args []Value) Value {
+    var fv Value
+    var nb Int
+    var ok bool
+
+    /* check for arguments */
+    if len(args) != 1 && len(args) != 2 {
+        panic(MakeError(ErrArity, "make-vector: proc requires 1 or 2 arguments"))
+    }
+
+    /* check for vector size */
+    if nb, ok = args[0].(Int); !ok || nb < 0 {
+        panic(MakeError(ErrType, "make-vector: invalid vector size", args[0]))
+    }
+
+    /* refuse to allocate absurdly large vectors */
+    if nb > MaxVectorSize {
+        panic(MakeError(ErrRange, "make-vector: vector size out of range", nb))
+    }
+
+    /* check for optional fill value */
+    if len(args) == 2 {
+        fv = args[1]
+    }
+
+    /* construct the vector */
+    ret := make([]Value, nb)
+    for i := range ret { ret[i] = fv }
+    return MakeVector(ret)
+}
+
+func intrinsicsVector(args []Value) Value {
+    return MakeVector(append([]Value(nil), args...))
+}
+
+func intrinsicsIsVector(args []Value) Value {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "vector?: proc takes exact 1 argument"))
+    } else {
+        _, ok := args[0].(*Vector)
+        return Bool(ok)
+    }
+}
+
+func intrinsicsVectorLength(args []Value) Value {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "vector-length: proc takes exact 1 argument"))
+    } else {
+        return Int(len(asVector("vector-length", args[0]).Elems))
+    }
+}
+
+func intrinsicsVectorRef(args []Value) Value {
+    if len(args) != 2 {
+        panic(MakeError(ErrArity, "vector-ref: proc takes exact 2 arguments"))
+    } else {
+        vec := asVector("vector-ref", args[0])
+        return vec.Elems[asIndex("vector-ref", args[1], len(vec.Elems) - 1)]
+    }
+}
+
+func intrinsicsVectorSet(args []Value) Value {
+    if len(args) != 3 {
+        panic(MakeError(ErrArity, "vector-set!: proc takes exact 3 arguments"))
+    } else {
+        vec := asVector("vector-set!", args[0])
+        vec.Elems[asIndex("vector-set!", args[1], len(vec.Elems) - 1)] = args[2]
+        return Unspecified{}
+    }
+}
+
+func intrinsicsVectorSwap(args []Value) Value {
+    if len(args) != 3 {
+        panic(MakeError(ErrArity, "vector-swap!: proc takes exact 3 arguments"))
+    } else {
+        vec := asVector("vector-swap!", args[0])
+        i := asIndex("vector-swap!", args[1], len(vec.Elems) - 1)
+        j := asIndex("vector-swap!", args[2], len(vec.Elems) - 1)
+        vec.Elems[i], vec.Elems[j] = vec.Elems[j], vec.Elems[i]
+        return Unspecified{}
+    }
+}
+
+func intrinsicsVectorFill(args []Value) Value {
+    if len(args) < 2 {
+        panic(MakeError(ErrArity, "vector-fill!: proc requires at least 2 arguments"))
+    } else {
+        vec := asVector("vector-fill!", args[0])
+        i, j := vectorRange("vector-fill!", vec, args[2:])
+        for ; i < j; i++ { vec.Elems[i] = args[1] }
+        return Unspecified{}
+    }
+}
+
+func intrinsicsVectorReverse(args []Value) Value {
+    if len(args) < 1 {
+        panic(MakeError(ErrArity, "vector-reverse!: proc requires at least 1 argument"))
+    } else {
+        vec := asVector("vector-reverse!", args[0])
+        i, j := vectorRange("vector-reverse!", vec, args[1:])
+        for j--; i < j; i, j = i + 1, j - 1 {
+            vec.Elems[i], vec.Elems[j] = vec.Elems[j], vec.Elems[i]
+        }
+        return Unspecified{}
+    }
+}
+
+func intrinsicsVectorCopy(args []Value) Value {
+    if len(args) < 1 {
+        panic(MakeError(ErrArity, "vector-copy: proc requires at least 1 argument"))
+    } else {
+        vec := asVector("vector-copy", args[0])
+        i, j := vectorRange("vector-copy", vec, args[1:])
+        return MakeVector(append([]Value(nil), vec.Elems[i:j]...))
+    }
+}
+
+func intrinsicsVectorCopyTo(args []Value) Value {
+    if len(args) < 3 {
+        panic(MakeError(ErrArity, "vector-copy!: proc requires at least 3 arguments"))
+    }
+
+    /* extract the source and destination */
+    dst := asVector("vector-copy!", args[0])
+    src := asVector("vector-copy!", args[2])
+    pos := asIndex("vector-copy!", args[1], len(dst.Elems))
+
+    /* check for destination space */
+    if i, j := vectorRange("vector-copy!", src, args[3:]); j - i > len(dst.Elems) - pos {
+        panic(MakeError(ErrRuntime, "vector-copy!: not enough space in destination vector"))
+    } else {
+        copy(dst.Elems[pos:], src.Elems[i:j])
+        return Unspecified{}
+    }
+}
+
+func intrinsicsVectorAppend(args []Value) Value {
+    var ret []Value
+    for _, v := range args { ret = append(ret, asVector("vector-append", v).Elems...) }
+    return MakeVector(ret)
+}
+
+func intrinsicsVectorToList(args []Value) Value {
+    if len(args) < 1 {
+        panic(MakeError(ErrArity, "vector->list: proc requires at least 1 argument"))
+    } else {
+        vec := asVector("vector->list", args[0])
+        i, j := vectorRange("vector->list", vec, args[1:])
+        return MakeList(vec.Elems[i:j]...)
+    }
+}
+
+func intrinsicsListToVector(args []Value) Value {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "list->vector: proc takes exact 1 argument"))
+    } else {
+        return MakeVector(asSlice("list->vector", args[0]))
+    }
+}
+
+func intrinsicsVectorMap(args []Value) Value {
+    fn, vv, nb := vectorsArgs("vector-map", args)
+    ret := make([]Value, nb)
+
+    /* map over every element */
+    for i := range ret {
+        ret[i] = vectorsCall(fn, vv, i)
+    }
+
+    /* construct the new vector */
+    return MakeVector(ret)
+}
+
+func intrinsicsVectorForEach(args []Value) Value {
+    fn, vv, nb := vectorsArgs("vector-for-each", args)
+    for i := 0; i < nb; i++ { vectorsCall(fn, vv, i) }
+    return Unspecified{}
+}
+
+func intrinsicsVectorCount(args []Value) Value {
+    ret := 0
+    fn, vv, nb := vectorsArgs("vector-count", args)
+
+    /* count the elements that satisfy the predicate */
+    for i := 0; i < nb; i++ {
+        if istrue(vectorsCall(fn, vv, i)) {
+            ret++
+        }
+    }
+
+    /* all done */
+    return Int(ret)
+}
+
+func intrinsicsVectorIndex(args []Value) Value {
+    fn, vv, nb := vectorsArgs("vector-index", args)
+
+    /* find the first element that satisfies the predicate */
+    for i := 0; i < nb; i++ {
+        if istrue(vectorsCall(fn, vv, i)) {
+            return Int(i)
+        }
+    }
+
+    /* not found */
+    return Bool(false)
+}
+
+func intrinsicsVectorAny(args []Value) Value {
+    fn, vv, nb := vectorsArgs("vector-any", args)
+
+    /* find the first element that satisfies the predicate */
+    for i := 0; i < nb; i++ {
+        if r := vectorsCall(fn, vv, i); istrue(r) {
+            return r
+        }
+    }
+
+    /* not found */
+    return Bool(false)
+}
+
+func intrinsicsVectorEvery(args []Value) Value {
+    var ret Value = Bool(true)
+    fn, vv, nb := vectorsArgs("vector-every", args)
+    for i := 0; i < nb && istrue(ret); i++ { ret = vectorsCall(fn, vv, i) }
+    return ret
+}
+
+func intrinsicsVectorFold(args []Value) Value {
+    if len(args) < 3 {
+        panic(MakeError(ErrArity, "vector-fold: proc requires at least 3 arguments"))
+    }
+
+    /* extract the initial state, and the remaining arguments */
+    ret := args[1]
+    fn, vv, nb := vectorsArgs("vector-fold", append([]Value{args[0]}, args[2:]...))
+
+    /* fold over every element */
+    for i := 0; i < nb; i++ {
+        ret = vectorsCall(fn, vv, i, ret)
+    }
+
+    /* all done */
+    return ret
+}
+
+func init() {
+    RegisterIntrinsic("make-vector", intrinsicsMakeVector)
+    RegisterIntrinsic("vector", intrinsicsVector)
+    RegisterIntrinsic("vector?", intrinsicsIsVector)
+    RegisterIntrinsic("vector-length", intrinsicsVectorLength)
+    RegisterIntrinsic("vector-ref", intrinsicsVectorRef)
+    RegisterIntrinsic("vector-set!", intrinsicsVectorSet)
+    RegisterIntrinsic("vector-swap!", intrinsicsVectorSwap)
+    RegisterIntrinsic("vector-fill!", intrinsicsVectorFill)
+    RegisterIntrinsic("vector-reverse!", intrinsicsVectorReverse)
+    RegisterIntrinsic("vector-copy", intrinsicsVectorCopy)
+    RegisterIntrinsic("vector-copy!", intrinsicsVectorCopyTo)
+    RegisterIntrinsic("vector-append", intrinsicsVectorAppend)
+    RegisterIntrinsic("vector->list", intrinsicsVectorToList)
+    RegisterIntrinsic("list->vector", intrinsicsListToVector)
+    RegisterIntrinsic("vector-map", intrinsicsVectorMap)
+    RegisterIntrinsic("vector-for-each", intrinsicsVectorForEach)
+    RegisterIntrinsic("vector-count", intrinsicsVectorCount)
+    RegisterIntrinsic("vector-index", intrinsicsVectorIndex)
+    RegisterIntrinsic("vector-any", intrinsicsVectorAny)
+    RegisterIntrinsic("vector-every", intrinsicsVectorEvery)
+    RegisterIntrinsic("vector-fold", intrinsicsVectorFold)
+}
+
+/** Input / Output Functions **/
+
+func intrinsicsDisplay(it *Interpreter, args []Value) Value {
+    var ok bool
+    var wp *Port
+
+    /* check for arguments */
+    if len(args) != 1 && len(args) != 2 {
+        panic(MakeError(ErrArity, "display: proc requires 1 or 2 arguments"))
+    }
+
+    /* check for optional port */
+    if wp = it.Stdout; len(args) == 2 {
+        if wp, ok = args[1].(*Port); !ok {
+            panic(MakeError(ErrType, "display: object is not a port", args[1]))
+        }
+    }
+
+    /* display the value */
+    wp.Write([]byte(AsDisplay(args[0])))
+    return Unspecified{}
+}
+
+func intrinsicsNewline(it *Interpreter, args []Value) Value {
+    var ok bool
+    var wp *Port
+
+    /* check for arguments */
+    if len(args) != 0 && len(args) != 1 {
+        panic(MakeError(ErrArity, "newline: proc requires 1 or 2 arguments"))
+    }
+
+    /* check for optional port */
+    if wp = it.Stdout; len(args) == 1 {
+        if wp, ok = args[0].(*Port); !ok {
+            panic(MakeError(ErrType, "newline: object is not a port", args[0]))
+        }
+    }
+
+    /* display the newline */
+    wp.Write([]byte{'\n'})
+    return Unspecified{}
+}
+
+func intrinsicsCallWithOutputFile(args []Value) Value {
+    var ok bool
+    var fn String
+    var cb LoadedProc
+
+    /* extract the file name and callback */
+    if len(args) != 2                     { panic(MakeError(ErrArity, "call-with-output-file: proc requires exact 2 arguments")) }
+    if fn, ok = args[0].(String)    ; !ok { panic(MakeError(ErrType, "call-with-output-file: object is not a string", args[0])) }
+    if cb, ok = args[1].(LoadedProc); !ok { panic(MakeError(ErrType, "call-with-output-file: object is not a callable proc", args[1])) }
+
+    /* open a new port */
+    file := string(fn)
+    port := OpenFileWritePort(file)
+
+    /* call the function with the port */
+    defer port.Close()
+    return cb.Call([]Value{port})
+}
+
+func init() {
+    RegisterBoundIntrinsic("display", intrinsicsDisplay)
+    RegisterBoundIntrinsic("newline", intrinsicsNewline)
+    RegisterIntrinsic("call-with-output-file", intrinsicsCallWithOutputFile)
+}
+
+/** Error Handling Functions **/
+
+func asErrorObject(name string, v Value) *LispError {
+    if ev, ok := v.(*LispError); !ok {
+        panic(MakeError(ErrType, name + ": object is not an error object", v))
+    } else {
+        return ev
+    }
+}
+
+func intrinsicsIsErrorObject(args []Value) Value {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "error-object?: proc takes exact 1 argument"))
+    } else {
+        _, ok := args[0].(*LispError)
+        return Bool(ok)
+    }
+}
+
+func intrinsicsErrorObjectMessage(args []Value) Value {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "error-object-message: proc takes exact 1 argument"))
+    } else {
+        return String(asErrorObject("error-object-message", args[0]).Message)
+    }
+}
+
+func intrinsicsErrorObjectIrritants(args []Value) Value {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "error-object-irritants: proc takes exact 1 argument"))
+    } else {
+        return MakeList(asErrorObject("error-object-irritants", args[0]).Irritants...)
+    }
+}
+
+func intrinsicsIsFileError(args []Value) Value {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "file-error?: proc takes exact 1 argument"))
+    } else {
+        ev, ok := args[0].(*LispError)
+        return Bool(ok && ev.Kind == ErrIO)
+    }
+}
+
+func intrinsicsIsReadError(args []Value) Value {
+    if len(args) != 1 {
+        panic(MakeError(ErrArity, "read-error?: proc takes exact 1 argument"))
+    } else {
+        ev, ok := args[0].(*LispError)
+        return Bool(ok && ev.Kind == ErrSyntax)
+    }
+}
+
+func intrinsicsError(args []Value) Value {
+    if len(args) < 1 {
+        panic(MakeError(ErrArity, "error: proc requires at least 1 argument"))
+    } else if msg, ok := args[0].(String); !ok {
+        panic(MakeError(ErrType, "error: object is not a string", args[0]))
+    } else {
+        panic(MakeError(ErrUser, string(msg), append([]Value(nil), args[1:]...)...))
+    }
+}
+
+func intrinsicsWithErrorHandler(it *Interpreter, args []Value) (ret Value) {
+    if len(args) != 2 {
+        panic(MakeError(ErrArity, "with-error-handler: proc takes exact 2 arguments"))
+    }
+
+    /* extract the handler and thunk */
+    fn := asCallable("with-error-handler", args[0])
+    cb := asCallable("with-error-handler", args[1])
+
+    /* save the current dynamic extent, errors within the thunk are propagated as Go panics */
+    wind := it.winds
+    hand := it.handlers
+    it.handlers = &_Handler { prev: hand }
+
+    /* the handler is called with the error object, and it's result is returned */
+    defer func() {
+        v := recover()
+        it.handlers = hand
+
+        /* check for the recovered value */
+        if v == nil {
+            return
+        } else if _, ok := v.(*_Escape); ok {
+            panic(v)
+        } else {
+            it.rewind(wind)
+            ret = fn.Call([]Value{AsError(v)})
+        }
+    }()
+
+    /* call the thunk */
+    return cb.Call(nil)
+}
+
+func init() {
+    RegisterIntrinsic("error", intrinsicsError)
+    RegisterIntrinsic("error-object?", intrinsicsIsErrorObject)
+    RegisterIntrinsic("error-object-message", intrinsicsErrorObjectMessage)
+    RegisterIntrinsic("error-object-irritants", intrinsicsErrorObjectIrritants)
+    RegisterIntrinsic("file-error?", intrinsicsIsFileError)
+    RegisterIntrinsic("read-error?", intrinsicsIsReadError)
+    RegisterBoundIntrinsic("with-error-handler", intrinsicsWithErrorHandler)
+}
+
+/** Debugging Functions **/
+
+func intrinsicsDisassemble(it *Interpreter, args []Value) Value {
+    var ok bool
+    var fn LoadedProc
+
+    /* check for arguments */
+    if len(args) != 1 && len(args) != 2 {
+        panic(MakeError(ErrArity, "disassemble: proc requires 1 or 2 arguments"))
+    }
+
+    /* only compiled procs can be disassembled */
+    if fn, ok = args[0].(LoadedProc); !ok {
+        panic(MakeError(ErrType, "disassemble: object is not a compiled proc", args[0]))
+    }
+
+    /* the optional argument annotates every instruction with it's source line */
+    lines := len(args) == 2 && istrue(args[1])
+    it.Stdout.Write([]byte(fn.Disassemble(lines) + "\n"))
+    return Unspecified{}
+}
+
+func init() {
+    RegisterBoundIntrinsic("disassemble", intrinsicsDisassemble)
+}
+
+/** System Interface **/
+
+func intrinsicsCommandLine(it *Interpreter, args []Value) Value {
+    var p *List
+    var q *List
+
+    /* check for arguments */
+    if len(args) != 0 {
+        panic(MakeError(ErrArity, "command-line: proc takes no arguments"))
+    }
+
+    /* build the argument list */
+    for _, v := range it.CommandLine {
+        AppendValue(&p, &q, String(v))
+    }
+
+    /* all done */
+    return p
+}
+
+func init() {
+    RegisterBoundIntrinsic("command-line", intrinsicsCommandLine)
+}
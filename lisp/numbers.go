@@ -0,0 +1,343 @@
+package lisp
+
+import (
+    `math`
+    `math/big`
+)
+
+type NumKind uint8
+
+const (
+    NumInt NumKind = iota
+    NumBigInt
+    NumRational
+    NumFloat
+    NumComplex
+)
+
+func (self NumKind) Coerce(vt NumKind) NumKind {
+    if vt > self {
+        return vt
+    } else {
+        return self
+    }
+}
+
+/** Number Converting **/
+
+func AsNumber(v Value) Numerical {
+    if r, ok := v.(Numerical); !ok {
+        panic(MakeError(ErrType, "eval: object is not a number", v))
+    } else {
+        return r
+    }
+}
+
+func AsNumbers(v1 Value, v2 Value) (Numerical, Numerical, NumKind) {
+    r1, r2 := AsNumber(v1), AsNumber(v2)
+    return r1, r2, r1.Kind().Coerce(r2.Kind())
+}
+
+/** Number Arithmetic **/
+
+func NumberNeg(v Value) Value {
+    switch x := AsNumber(v); x.Kind() {
+        case NumInt      : return numberNegInt(x.AsInt())
+        case NumBigInt   : return MakeInteger(new(big.Int).Neg(x.AsBigInt().Int()))
+        case NumRational : return MakeRational(new(big.Rat).Neg(x.AsRational().Rat()))
+        case NumFloat    : return -x.AsFloat()
+        case NumComplex  : return -x.AsComplex()
+        default          : panic("-: unreachable")
+    }
+}
+
+func NumberInv(v Value) Value {
+    switch x := AsNumber(v); x.Kind() {
+        case NumInt      : fallthrough
+        case NumBigInt   : fallthrough
+        case NumRational : return NumberDiv(Int(1), x)
+        case NumFloat    : return 1.0 / x.AsFloat()
+        case NumComplex  : return 1.0 / x.AsComplex()
+        default          : panic("/: unreachable")
+    }
+}
+
+func NumberAdd(a Value, b Value) Value {
+    switch x, y, vt := AsNumbers(a, b); vt {
+        case NumInt      : return numberAddInt(x.AsInt(), y.AsInt())
+        case NumBigInt   : return MakeInteger(new(big.Int).Add(x.AsBigInt().Int(), y.AsBigInt().Int()))
+        case NumRational : return MakeRational(new(big.Rat).Add(x.AsRational().Rat(), y.AsRational().Rat()))
+        case NumFloat    : return x.AsFloat() + y.AsFloat()
+        case NumComplex  : return x.AsComplex() + y.AsComplex()
+        default          : panic("+: unreachable")
+    }
+}
+
+func NumberSub(a Value, b Value) Value {
+    switch x, y, vt := AsNumbers(a, b); vt {
+        case NumInt      : return numberSubInt(x.AsInt(), y.AsInt())
+        case NumBigInt   : return MakeInteger(new(big.Int).Sub(x.AsBigInt().Int(), y.AsBigInt().Int()))
+        case NumRational : return MakeRational(new(big.Rat).Sub(x.AsRational().Rat(), y.AsRational().Rat()))
+        case NumFloat    : return x.AsFloat() - y.AsFloat()
+        case NumComplex  : return x.AsComplex() - y.AsComplex()
+        default          : panic("-: unreachable")
+    }
+}
+
+func NumberMul(a Value, b Value) Value {
+    switch x, y, vt := AsNumbers(a, b); vt {
+        case NumInt      : return numberMulInt(x.AsInt(), y.AsInt())
+        case NumBigInt   : return MakeInteger(new(big.Int).Mul(x.AsBigInt().Int(), y.AsBigInt().Int()))
+        case NumRational : return MakeRational(new(big.Rat).Mul(x.AsRational().Rat(), y.AsRational().Rat()))
+        case NumFloat    : return x.AsFloat() * y.AsFloat()
+        case NumComplex  : return x.AsComplex() * y.AsComplex()
+        default          : panic("*: unreachable")
+    }
+}
+
+func NumberDiv(a Value, b Value) Value {
+    switch x, y, vt := AsNumbers(a, b); vt {
+        case NumInt      : fallthrough
+        case NumBigInt   : fallthrough
+        case NumRational : return numberDivExact(x.AsRational(), y.AsRational())
+        case NumFloat    : return x.AsFloat() / y.AsFloat()
+        case NumComplex  : return x.AsComplex() / y.AsComplex()
+        default          : panic("/: unreachable")
+    }
+}
+
+func NumberRound(v Value) Value {
+    switch x := AsNumber(v); x.Kind() {
+        case NumInt      : fallthrough
+        case NumBigInt   : return v
+        case NumRational : return numberRoundExact(x.AsRational())
+        case NumFloat    : fallthrough
+        case NumComplex  : return Float(math.RoundToEven(float64(x.AsFloat())))
+        default          : panic("round: unreachable")
+    }
+}
+
+func NumberMagnitude(v Value) Value {
+    switch x := AsNumber(v); x.Kind() {
+        case NumInt      : fallthrough
+        case NumBigInt   : fallthrough
+        case NumRational : fallthrough
+        case NumFloat    : return v
+        case NumComplex  : return x.AsComplex().Magnitude()
+        default          : panic("magnitude: unreachable")
+    }
+}
+
+func NumberExact(v Value) Value {
+    switch x := AsNumber(v); x.Kind() {
+        case NumInt      : fallthrough
+        case NumBigInt   : fallthrough
+        case NumRational : return v
+        case NumFloat    : fallthrough
+        case NumComplex  : return MakeRational(x.AsRational().Rat())
+        default          : panic("exact: unreachable")
+    }
+}
+
+func NumberInexact(v Value) Value {
+    switch x := AsNumber(v); x.Kind() {
+        case NumInt      : fallthrough
+        case NumBigInt   : fallthrough
+        case NumRational : return x.AsFloat()
+        case NumFloat    : fallthrough
+        case NumComplex  : return v
+        default          : panic("inexact: unreachable")
+    }
+}
+
+func NumberNumerator(v Value) Value {
+    switch x := AsNumber(v); x.Kind() {
+        case NumInt      : fallthrough
+        case NumBigInt   : return v
+        case NumRational : return MakeInteger(new(big.Int).Set(x.AsRational().Rat().Num()))
+        case NumFloat    : return NumberInexact(NumberNumerator(NumberExact(v)))
+        case NumComplex  : panic(MakeError(ErrType, "numerator: object is not a rational number", v))
+        default          : panic("numerator: unreachable")
+    }
+}
+
+func NumberDenominator(v Value) Value {
+    switch x := AsNumber(v); x.Kind() {
+        case NumInt      : fallthrough
+        case NumBigInt   : return Int(1)
+        case NumRational : return MakeInteger(new(big.Int).Set(x.AsRational().Rat().Denom()))
+        case NumFloat    : return NumberInexact(NumberDenominator(NumberExact(v)))
+        case NumComplex  : panic(MakeError(ErrType, "denominator: object is not a rational number", v))
+        default          : panic("denominator: unreachable")
+    }
+}
+
+/** Integer Division **/
+
+func asInteger(name string, v Value) Numerical {
+    if x := AsNumber(v); x.Kind() != NumInt && x.Kind() != NumBigInt {
+        panic(MakeError(ErrType, name + ": object is not an integer", v))
+    } else {
+        return x
+    }
+}
+
+func asIntegers(name string, a Value, b Value) (Numerical, Numerical, NumKind) {
+    x := asInteger(name, a)
+    y := asInteger(name, b)
+
+    /* check for division by zero */
+    if y.Kind() == NumInt && y.AsInt() == 0 {
+        panic(MakeError(ErrRuntime, name + ": division by zero"))
+    } else {
+        return x, y, x.Kind().Coerce(y.Kind())
+    }
+}
+
+func NumberQuotient(a Value, b Value) Value {
+    if x, y, vt := asIntegers("quotient", a, b); vt == NumInt && (x.AsInt() != math.MinInt64 || y.AsInt() != -1) {
+        return x.AsInt() / y.AsInt()
+    } else {
+        return MakeInteger(new(big.Int).Quo(x.AsBigInt().Int(), y.AsBigInt().Int()))
+    }
+}
+
+func NumberRemainder(a Value, b Value) Value {
+    switch x, y, vt := asIntegers("remainder", a, b); vt {
+        case NumInt : return x.AsInt() % y.AsInt()
+        default     : return MakeInteger(new(big.Int).Rem(x.AsBigInt().Int(), y.AsBigInt().Int()))
+    }
+}
+
+func NumberModulo(a Value, b Value) Value {
+    x, y, _ := asIntegers("modulo", a, b)
+    r := AsNumber(NumberRemainder(x, y))
+
+    /* the result of modulo takes the sign of the divisor */
+    if NumberCompareEq(r, Int(0)) || NumberCompareLt(r, Int(0)) == NumberCompareLt(y, Int(0)) {
+        return r
+    } else {
+        return NumberAdd(r, y)
+    }
+}
+
+/** Number Comparison **/
+
+func NumberCompareEq(a Value, b Value) bool {
+    switch x, y, vt := AsNumbers(a, b); vt {
+        case NumInt      : return x.AsInt() == y.AsInt()
+        case NumBigInt   : return x.AsBigInt().Int().Cmp(y.AsBigInt().Int()) == 0
+        case NumRational : return x.AsRational().Rat().Cmp(y.AsRational().Rat()) == 0
+        case NumFloat    : return x.AsFloat() == y.AsFloat()
+        case NumComplex  : return x.AsComplex() == y.AsComplex()
+        default          : panic("=: unreachable")
+    }
+}
+
+func NumberCompareLt(a Value, b Value) bool {
+    switch x, y, vt := AsNumbers(a, b); vt {
+        case NumInt      : return x.AsInt() < y.AsInt()
+        case NumBigInt   : return x.AsBigInt().Int().Cmp(y.AsBigInt().Int()) < 0
+        case NumRational : return x.AsRational().Rat().Cmp(y.AsRational().Rat()) < 0
+        case NumFloat    : return x.AsFloat() < y.AsFloat()
+        case NumComplex  : panic(MakeError(ErrType, "<: complex numbers can only be compared for equality"))
+        default          : panic("<: unreachable")
+    }
+}
+
+func NumberCompareGt(a Value, b Value) bool {
+    switch x, y, vt := AsNumbers(a, b); vt {
+        case NumInt      : return x.AsInt() > y.AsInt()
+        case NumBigInt   : return x.AsBigInt().Int().Cmp(y.AsBigInt().Int()) > 0
+        case NumRational : return x.AsRational().Rat().Cmp(y.AsRational().Rat()) > 0
+        case NumFloat    : return x.AsFloat() > y.AsFloat()
+        case NumComplex  : panic(MakeError(ErrType, ">: complex numbers can only be compared for equality"))
+        default          : panic(">: unreachable")
+    }
+}
+
+func NumberCompareLte(a Value, b Value) bool {
+    switch x, y, vt := AsNumbers(a, b); vt {
+        case NumInt      : return x.AsInt() <= y.AsInt()
+        case NumBigInt   : return x.AsBigInt().Int().Cmp(y.AsBigInt().Int()) <= 0
+        case NumRational : return x.AsRational().Rat().Cmp(y.AsRational().Rat()) <= 0
+        case NumFloat    : return x.AsFloat() <= y.AsFloat()
+        case NumComplex  : panic(MakeError(ErrType, "<=: complex numbers can only be compared for equality"))
+        default          : panic("<=: unreachable")
+    }
+}
+
+func NumberCompareGte(a Value, b Value) bool {
+    switch x, y, vt := AsNumbers(a, b); vt {
+        case NumInt      : return x.AsInt() >= y.AsInt()
+        case NumBigInt   : return x.AsBigInt().Int().Cmp(y.AsBigInt().Int()) >= 0
+        case NumRational : return x.AsRational().Rat().Cmp(y.AsRational().Rat()) >= 0
+        case NumFloat    : return x.AsFloat() >= y.AsFloat()
+        case NumComplex  : panic(MakeError(ErrType, ">=: complex numbers can only be compared for equality"))
+        default          : panic(">=: unreachable")
+    }
+}
+
+/** Fixed-width Integer Helpers **/
+
+func numberNegInt(x Int) Value {
+    if x == math.MinInt64 {
+        return MakeInteger(new(big.Int).Neg(x.AsBigInt().Int()))
+    } else {
+        return -x
+    }
+}
+
+func numberAddInt(x Int, y Int) Value {
+    if r := x + y; (x ^ r) & (y ^ r) >= 0 {
+        return r
+    } else {
+        return MakeInteger(new(big.Int).Add(x.AsBigInt().Int(), y.AsBigInt().Int()))
+    }
+}
+
+func numberSubInt(x Int, y Int) Value {
+    if r := x - y; (x ^ y) & (x ^ r) >= 0 {
+        return r
+    } else {
+        return MakeInteger(new(big.Int).Sub(x.AsBigInt().Int(), y.AsBigInt().Int()))
+    }
+}
+
+func numberMulInt(x Int, y Int) Value {
+    if x == 0 || y == 0 {
+        return Int(0)
+    } else if r := x * y; r / y == x && !(x == -1 && y == math.MinInt64) && !(y == -1 && x == math.MinInt64) {
+        return r
+    } else {
+        return MakeInteger(new(big.Int).Mul(x.AsBigInt().Int(), y.AsBigInt().Int()))
+    }
+}
+
+/** Exact Number Helpers **/
+
+func numberDivExact(x *Rational, y *Rational) Value {
+    if y.Rat().Sign() == 0 {
+        panic(MakeError(ErrRuntime, "/: division by zero"))
+    } else {
+        return MakeRational(new(big.Rat).Quo(x.Rat(), y.Rat()))
+    }
+}
+
+func numberRoundExact(x *Rational) Value {
+    var m big.Int
+    var q big.Int
+    var r big.Rat
+
+    /* floor division of numerator by denominator */
+    q.DivMod(x.Rat().Num(), x.Rat().Denom(), &m)
+    r.SetFrac(&m, x.Rat().Denom())
+
+    /* round half to even */
+    if c := r.Cmp(big.NewRat(1, 2)); c > 0 || (c == 0 && q.Bit(0) != 0) {
+        q.Add(&q, big.NewInt(1))
+    }
+
+    /* convert to integer */
+    return MakeInteger(&q)
+}
@@ -0,0 +1,43 @@
+package lisp
+
+import (
+    `testing`
+
+    `github.com/stretchr/testify/require`
+)
+
+func TestNumber_Rational(t *testing.T) {
+    tests := [][2]string {
+        { "(/ 1 3)"                                    , "1/3" },
+        { "(+ 1/3 2/3)"                                , "1" },
+        { "(* 1/3 0.5)"                                , "0.16666666666666666" },
+        { "(/ 6 3)"                                    , "2" },
+        { "(- 1/2)"                                    , "-1/2" },
+        { "(list (numerator 6/4) (denominator 6/4))"   , "(3 2)" },
+        { "(inexact->exact 2.5)"                       , "5/2" },
+        { "(list (round 5/2) (round 7/2) (round -5/2))", "(2 4 -2)" },
+        { "(< 1/3 0.34)"                               , "#t" },
+    }
+    for _, ts := range tests {
+        require.Equal(t, ts[1], AsString(evalsrc(ts[0])), ts[0])
+    }
+    require.PanicsWithError(t, "/: division by zero", func() { evalsrc("(/ 1 0)") })
+}
+
+func TestNumber_BigInt(t *testing.T) {
+    src := "(define (fac n) (if (= n 0) 1 (* n (fac (- n 1)))))"
+    tests := [][2]string {
+        { "(fac 21)"                                                         , "51090942171709440000" },
+        { "(quotient (fac 30) (fac 28))"                                     , "870" },
+        { "(list (modulo -7 2) (remainder -7 2) (modulo 7 -2))"              , "(1 -1 -1)" },
+        { "(- 123456789012345678901234567891 123456789012345678901234567890)", "1" },
+        { "(/ (fac 25) (fac 27))"                                            , "1/702" },
+        { "(+ 9223372036854775807 1)"                                        , "9223372036854775808" },
+        { "(- -9223372036854775808 1)"                                       , "-9223372036854775809" },
+        { "(quotient -9223372036854775808 -1)"                               , "9223372036854775808" },
+    }
+    for _, ts := range tests {
+        require.Equal(t, ts[1], AsString(evalsrc(src + ts[0])), ts[0])
+    }
+    require.PanicsWithError(t, "modulo: division by zero", func() { evalsrc("(modulo 1 0)") })
+}
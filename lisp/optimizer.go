@@ -0,0 +1,298 @@
+package lisp
+
+func isBranch(op OpCode) bool {
+    switch op {
+        case OP_goto         : return true
+        case OP_if_false     : return true
+        case OP_assert_true  : return true
+        case OP_assert_false : return true
+        default              : return false
+    }
+}
+
+func isFallThrough(op OpCode) bool {
+    return op != OP_goto && op != OP_return
+}
+
+func isConstant(iv Instr) bool {
+    return iv.Op() == OP_ldconst && iv.Iv() == 0
+}
+
+func jumpTargets(p Program) []bool {
+    ret := make([]bool, len(p) + 1)
+
+    /* mark every branch target */
+    for _, iv := range p {
+        if isBranch(iv.Op()) {
+            ret[iv.Iv()] = true
+        }
+    }
+
+    /* all done */
+    return ret
+}
+
+/** Optimization Passes **/
+
+func optimizeJumps(p Program, dead []bool) (ret bool) {
+    for i, iv := range p {
+        if isBranch(iv.Op()) {
+            pc := int(iv.Iv())
+
+            /* follow the "goto" chains, limited to the program size to avoid infinite loops */
+            for n := len(p); n != 0 && p[pc].Op() == OP_goto && int(p[pc].Iv()) != pc; n-- {
+                pc = int(p[pc].Iv())
+            }
+
+            /* retarget the branch */
+            if pc != int(iv.Iv()) {
+                p[i].u1 = uint32(pc)
+                ret = true
+            }
+
+            /* jumping to the next instruction is not necessary */
+            if iv.Op() == OP_goto && pc == i + 1 {
+                dead[i] = true
+                ret = true
+            }
+        }
+    }
+    return
+}
+
+func optimizeBranches(p Program, dead []bool) (ret bool) {
+    jt := jumpTargets(p)
+
+    /* find all the constant conditions, folded constants are not known until runtime */
+    for i := 0; i < len(p) - 1; i++ {
+        if isConstant(p[i]) && !jt[i + 1] && !dead[i] && !dead[i + 1] {
+            cond := istrue(p[i].Rv())
+            next := mkins(OP_goto, p[i + 1].Iv(), "", nil, nil).withLine(p[i + 1].Line())
+
+            /* the condition is known at compile time */
+            switch p[i + 1].Op() {
+                default: {
+                    continue
+                }
+
+                /* conditional branches consume the constant */
+                case OP_if_false: {
+                    if dead[i] = true; cond {
+                        dead[i + 1] = true
+                    } else {
+                        p[i + 1] = next
+                    }
+                }
+
+                /* assertions keep the constant on stack when branching */
+                case OP_assert_true: {
+                    if !cond {
+                        p[i + 1] = next
+                    } else {
+                        dead[i], dead[i + 1] = true, true
+                    }
+                }
+
+                /* assertions keep the constant on stack when branching */
+                case OP_assert_false: {
+                    if cond {
+                        p[i + 1] = next
+                    } else {
+                        dead[i], dead[i + 1] = true, true
+                    }
+                }
+            }
+
+            /* skip the branch instruction */
+            ret = true
+            i++
+        }
+    }
+    return
+}
+
+func optimizeConstants(p Program, dead []bool) (ret bool) {
+    jt := jumpTargets(p)
+
+    /* fold the primitive operations on constant operands, guarded by the primitive operators since they can be rebound */
+    for i := 0; i < len(p) - 2; i++ {
+        if p[i].Op() == OP_ldconst && p[i + 1].Op() == OP_ldconst && !jt[i + 1] && !jt[i + 2] {
+            if pp := _PrimitiveTab[p[i + 2].Op()]; pp != nil && !dead[i] && !dead[i + 1] && !dead[i + 2] {
+                if rv, ok := foldPrimitive(pp, foldedValue(p[i]), foldedValue(p[i + 1])); ok {
+                    p[i] = foldInstr(p[i:i + 3], pp, rv)
+                    dead[i + 1], dead[i + 2] = true, true
+                    ret = true
+                    i += 2
+                }
+            }
+        }
+    }
+
+    /* fold the pair accessors on constant pairs */
+    for i := 0; i < len(p) - 1; i++ {
+        if isConstant(p[i]) && !jt[i + 1] && !dead[i] && !dead[i + 1] {
+            if pv, ok := p[i].Rv().(*List); ok && pv != nil {
+                switch p[i + 1].Op() {
+                    case OP_car : p[i] = mkins(OP_ldconst, 0, "", pv.Car, nil).withLine(p[i + 1].Line())
+                    case OP_cdr : p[i] = mkins(OP_ldconst, 0, "", pv.Cdr, nil).withLine(p[i + 1].Line())
+                    default     : continue
+                }
+
+                /* the accessor is no longer needed */
+                dead[i + 1] = true
+                ret = true
+                i++
+            }
+        }
+    }
+    return
+}
+
+func optimizeDrops(p Program, dead []bool) (ret bool) {
+    jt := jumpTargets(p)
+
+    /* values without side effects that are dropped immediately, folded constants might call the rebound operators */
+    for i := 0; i < len(p) - 1; i++ {
+        if p[i + 1].Op() == OP_drop && !jt[i + 1] && !dead[i] && !dead[i + 1] {
+            switch {
+                case isConstant(p[i])        : break
+                case p[i].Op() == OP_ldproc  : break
+                case p[i].Op() == OP_ldlocal : break
+                default                      : continue
+            }
+
+            /* remove both instructions */
+            dead[i], dead[i + 1] = true, true
+            ret = true
+            i++
+        }
+    }
+    return
+}
+
+func optimizeUnreachable(p Program, dead []bool) (ret bool) {
+    pc := []int { 0 }
+    rt := make([]bool, len(p))
+
+    /* mark every reachable instruction */
+    for len(pc) != 0 {
+        i := pc[len(pc) - 1]
+        pc = pc[:len(pc) - 1]
+
+        /* follow the instruction flow */
+        for ; i < len(p) && !rt[i]; i++ {
+            if rt[i] = true; isBranch(p[i].Op()) {
+                pc = append(pc, int(p[i].Iv()))
+            }
+
+            /* check for unconditional control transfers */
+            if !isFallThrough(p[i].Op()) {
+                break
+            }
+        }
+    }
+
+    /* remove all the unreachable instructions */
+    for i, ok := range rt {
+        if !ok && !dead[i] {
+            dead[i] = true
+            ret = true
+        }
+    }
+    return
+}
+
+/** Constant Folding **/
+
+type _Folded struct {
+    val Value
+    fn  *Proc
+}
+
+func (self *_Folded) String() string {
+    return AsString(self.val)
+}
+
+func (self *_Folded) IsIdentity() bool {
+    return true
+}
+
+func foldedValue(iv Instr) Value {
+    if isConstant(iv) {
+        return iv.Rv()
+    } else {
+        return iv.Rv().(*_Folded).val
+    }
+}
+
+func foldInstr(code []Instr, pp *_Primitive, rv Value) Instr {
+    fn := &Proc { Name: "#[fold " + pp.name + "]" }
+    fn.Code = append(fn.Code, code...)
+    fn.Code = append(fn.Code, mkins(OP_return, 0, "", nil, nil).withLine(code[2].Line()))
+
+    /* the original instructions are executed instead if any of the operators were rebound */
+    mask := pp.mask | code[0].Iv() | code[1].Iv()
+    return mkins(OP_ldconst, mask, "", &_Folded { val: rv, fn: fn }, nil).withLine(code[2].Line())
+}
+
+func foldPrimitive(pp *_Primitive, x Value, y Value) (ret Value, ok bool) {
+    if _, ok = x.(Numerical); !ok {
+        return nil, false
+    } else if _, ok = y.(Numerical); !ok {
+        return nil, false
+    }
+
+    /* leave the errors to runtime */
+    defer func() {
+        if recover() != nil {
+            ret, ok = nil, false
+        }
+    }()
+
+    /* evaluate the primitive */
+    return pp.proc(x, y), true
+}
+
+/** Program Compaction **/
+
+func compactProgram(p Program, dead []bool) Program {
+    nb := 0
+    pc := make([]int, len(p) + 1)
+
+    /* removed instructions are mapped to the next live instruction */
+    for i := range p {
+        if pc[i] = nb; !dead[i] {
+            nb++
+        }
+    }
+
+    /* copy all the live instructions */
+    pc[len(p)] = nb
+    ret := make(Program, 0, nb)
+
+    /* relocate the branch targets */
+    for i, iv := range p {
+        if !dead[i] {
+            if isBranch(iv.Op()) {
+                iv.u1 = uint32(pc[iv.Iv()])
+            }
+            ret = append(ret, iv)
+        }
+    }
+
+    /* all done */
+    return ret
+}
+
+func Optimize(p Program) Program {
+    for ok := true; ok; {
+        dead := make([]bool, len(p))
+        ok = optimizeJumps(p, dead)
+        ok = optimizeBranches(p, dead) || ok
+        ok = optimizeConstants(p, dead) || ok
+        ok = optimizeDrops(p, dead) || ok
+        ok = optimizeUnreachable(p, dead) || ok
+        p = compactProgram(p, dead)
+    }
+    return p
+}
@@ -0,0 +1,171 @@
+package lisp
+
+import (
+    `strings`
+    `testing`
+
+    `github.com/stretchr/testify/require`
+)
+
+func disasm(src string, opt bool) string {
+    prog := Compiler{NoOptimize: !opt}.Compile(CreateParser(src).Parse())
+    return strings.TrimSpace(prog.String())
+}
+
+func TestOptimizer_Optimize(t *testing.T) {
+    tests := []struct {
+        src string
+        old string
+        new string
+    } {{
+        src: `(display (+ (* 2 3) 4))`,
+        old: `
+Procedure "#[main]":
+
+0 :  ldvar       display
+1 :  ldconst     (int) 2
+2 :  ldconst     (int) 3
+3 :  mul
+4 :  ldconst     (int) 4
+5 :  add
+6 :  tailcall    #2
+7 :  return`,
+        new: `
+Procedure "#[main]":
+
+0 :  ldvar       display
+1 :  ldconst     (int) 10
+2 :  tailcall    #2
+3 :  return`,
+    }, {
+        src: `(if #t (display 1) (display 2))`,
+        old: `
+Procedure "#[main]":
+
+0 :  ldconst     #t
+1 :  if.#f       @6
+2 :  ldvar       display
+3 :  ldconst     (int) 1
+4 :  tailcall    #2
+5 :  goto        @9
+6 :  ldvar       display
+7 :  ldconst     (int) 2
+8 :  tailcall    #2
+9 :  return`,
+        new: `
+Procedure "#[main]":
+
+0 :  ldvar       display
+1 :  ldconst     (int) 1
+2 :  tailcall    #2
+3 :  return`,
+    }, {
+        src: `(if (and #f x) 1 2)`,
+        old: `
+Procedure "#[main]":
+
+0 :  ldconst     #f
+1 :  assert.#t   @3
+2 :  ldvar       x
+3 :  if.#f       @6
+4 :  ldconst     (int) 1
+5 :  goto        @7
+6 :  ldconst     (int) 2
+7 :  return`,
+        new: `
+Procedure "#[main]":
+
+0 :  ldconst     (int) 2
+1 :  return`,
+    }, {
+        src: `(begin 1 'a (display (car '(1 2))))`,
+        old: `
+Procedure "#[main]":
+
+0 :  ldconst     (int) 1
+1 :  drop
+2 :  ldconst     a
+3 :  drop
+4 :  ldvar       display
+5 :  ldconst     (1 2)
+6 :  car
+7 :  tailcall    #2
+8 :  return`,
+        new: `
+Procedure "#[main]":
+
+0 :  ldvar       display
+1 :  ldconst     (int) 1
+2 :  tailcall    #2
+3 :  return`,
+    }, {
+        src: `(if x (if y 1 2) 3)`,
+        old: `
+Procedure "#[main]":
+
+0 :  ldvar       x
+1 :  if.#f       @8
+2 :  ldvar       y
+3 :  if.#f       @6
+4 :  ldconst     (int) 1
+5 :  goto        @7
+6 :  ldconst     (int) 2
+7 :  goto        @9
+8 :  ldconst     (int) 3
+9 :  return`,
+        new: `
+Procedure "#[main]":
+
+0 :  ldvar       x
+1 :  if.#f       @8
+2 :  ldvar       y
+3 :  if.#f       @6
+4 :  ldconst     (int) 1
+5 :  goto        @9
+6 :  ldconst     (int) 2
+7 :  goto        @9
+8 :  ldconst     (int) 3
+9 :  return`,
+    }, {
+        src: `(/ 1 0)`,
+        old: `
+Procedure "#[main]":
+
+0 :  ldconst     (int) 1
+1 :  ldconst     (int) 0
+2 :  div
+3 :  return`,
+        new: `
+Procedure "#[main]":
+
+0 :  ldconst     (int) 1
+1 :  ldconst     (int) 0
+2 :  div
+3 :  return`,
+    }}
+    for _, ts := range tests {
+        require.Equal(t, strings.TrimSpace(ts.old), disasm(ts.src, false), ts.src)
+        require.Equal(t, strings.TrimSpace(ts.new), disasm(ts.src, true), ts.src)
+    }
+}
+
+func TestOptimizer_Rebound(t *testing.T) {
+    require.Equal(t, "(3 -1)", AsString(evalsrc("(define (f) (+ 1 2)) (define a (f)) (set! + -) (list a (f))")))
+    require.Equal(t, "(1 2)", AsString(evalsrc("(define (f) (if (< 1 2) 1 2)) (define a (f)) (set! < >) (list a (f))")))
+    require.Equal(t, "(10 3 9)", AsString(evalsrc("(define (f) (+ (* 2 3) 4)) (define a (f)) (set! * -) (define b (f)) (set! * +) (list a b (f))")))
+    require.Equal(t, "(3 (1 2))", AsString(evalsrc("(define (f) (+ 1 2) (g)) (define (g) 3) (define a (f)) (define p '()) (set! + (lambda (x y) (set! p (list x y)) 0)) (f) (list a p)")))
+}
+
+func TestOptimizer_Folded(t *testing.T) {
+    it := CreateInterpreter()
+    fn, err := it.Eval("(define (f) (+ (* 2 3) 4)) f")
+    require.NoError(t, err)
+    code := fn.(LoadedProc).Code
+    require.Equal(t, "ldconst     (int) 10", code[0].String())
+    require.Equal(t, _PrimitiveTab[OP_add].mask | _PrimitiveTab[OP_mul].mask, code[0].Iv())
+    require.Zero(t, it.rebound)
+    v, err := it.Eval("(set! + -) (f)")
+    require.NoError(t, err)
+    require.Equal(t, Int(2), v)
+    require.Equal(t, _PrimitiveTab[OP_add].mask, it.rebound)
+}
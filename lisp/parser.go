@@ -0,0 +1,414 @@
+package lisp
+
+import (
+    `bufio`
+    `io`
+    `math/big`
+    `strconv`
+    `strings`
+)
+
+const (
+    _EOF = -1
+)
+
+var _CharTab = map[string]rune {
+    "space"     : ' ',
+    "newline"   : '\n',
+    "backspace" : '\b',
+    "tab"       : '\t',
+    "linefeed"  : '\n',
+    "page"      : '\f',
+    "return"    : '\r',
+    "rubout"    : 0x7f,
+}
+
+var _SpaceTab = [...]bool {
+    ' '  : true,
+    '\t' : true,
+    '\n' : true,
+    '\v' : true,
+    '\f' : true,
+    '\r' : true,
+}
+
+func isSpace(ch rune) bool {
+    return ch >= 0 && int(ch) < len(_SpaceTab) && _SpaceTab[ch]
+}
+
+func isAtomChar(ch rune) bool {
+    return !(ch == _EOF || ch == '(' || ch == ')' || ch == '"' || ch == '`' || ch == ',' || ch == ';' || isSpace(ch))
+}
+
+func isZeroRational(val string) bool {
+    if p := strings.IndexByte(val, '/'); p < 0 {
+        return false
+    } else if _, ok := new(big.Int).SetString(val[:p], 10); !ok {
+        return false
+    } else if dv, ok := new(big.Int).SetString(val[p + 1:], 10); !ok {
+        return false
+    } else {
+        return dv.Sign() == 0
+    }
+}
+
+type Parser struct {
+    rd  *bufio.Reader
+    la  []rune
+    sm  SourceMap
+    pos Span
+    eof bool
+}
+
+func CreateParser(src string) *Parser {
+    return CreateNamedParser("<string>", src)
+}
+
+func CreateNamedParser(name string, src string) *Parser {
+    return CreateStreamParser(name, strings.NewReader(src))
+}
+
+func CreateStreamParser(name string, rd io.Reader) *Parser {
+    return &Parser {
+        rd  : bufio.NewReader(rd),
+        sm  : make(SourceMap),
+        pos : Span { File: name, Row: 1, Col: 1 },
+    }
+}
+
+func (self *Parser) error(msg string) *LispError {
+    return self.errorAt(self.pos, msg)
+}
+
+func (self *Parser) errorAt(sp Span, msg string) *LispError {
+    return MakeError(ErrSyntax, "syntax error: " + msg).At(sp)
+}
+
+func (self *Parser) errorEOF(sp Span, msg string) *LispError {
+    self.eof = true
+    return self.errorAt(sp, msg)
+}
+
+func (self *Parser) Spans() SourceMap {
+    return self.sm
+}
+
+func (self *Parser) Incomplete() bool {
+    return self.eof
+}
+
+func (self *Parser) noEOF(topLevel bool) {
+    if !topLevel && self.peekChar(0) == _EOF {
+        panic(self.errorEOF(self.pos, "unexpected EOF"))
+    }
+}
+
+func (self *Parser) noSpace() {
+    for {
+        switch ch := self.peekChar(0); {
+            case isSpace(ch)          : self.nextChar()
+            case ch == ';'            : self.skipLine()
+            case self.hasPrefix("#|") : self.skipBlock()
+            case self.hasPrefix("#;") : self.skipDatum()
+            default                   : return
+        }
+    }
+}
+
+func (self *Parser) hasPrefix(s string) bool {
+    for i, ch := range []rune(s) {
+        if self.peekChar(i) != ch {
+            return false
+        }
+    }
+    return true
+}
+
+func (self *Parser) skipShebang() {
+    if self.pos.Row == 1 && self.pos.Col == 1 && self.hasPrefix("#!") {
+        self.skipLine()
+    }
+}
+
+func (self *Parser) skipLine() {
+    for ch := self.peekChar(0); ch != _EOF && ch != '\n'; ch = self.peekChar(0) {
+        self.nextChar()
+    }
+}
+
+func (self *Parser) skipBlock() {
+    n := 0
+    sp := self.pos
+
+    /* block comments can be nested */
+    for self.peekChar(0) != _EOF {
+        switch {
+            case self.hasPrefix("#|") : n++; self.skipChars(2)
+            case self.hasPrefix("|#") : n--; self.skipChars(2)
+            default                   : self.nextChar()
+        }
+
+        /* check for the outer-most comment */
+        if n == 0 {
+            return
+        }
+    }
+
+    /* report the error at the start of the comment */
+    panic(self.errorEOF(sp, "block comment is not terminated"))
+}
+
+func (self *Parser) skipDatum() {
+    self.skipChars(2)
+    vv, ok := self.parseValue(false)
+
+    /* the commented datum must present */
+    if !ok || vv == Atom(")") {
+        panic(self.error("datum expected after #;"))
+    }
+}
+
+func (self *Parser) skipChars(n int) {
+    for i := 0; i < n; i++ {
+        self.nextChar()
+    }
+}
+
+func (self *Parser) peekChar(i int) rune {
+    for len(self.la) <= i {
+        if ch, _, err := self.rd.ReadRune(); err == nil {
+            self.la = append(self.la, ch)
+        } else if err == io.EOF {
+            return _EOF
+        } else {
+            panic(MakeError(ErrIO, "io: read error: " + err.Error()).At(self.pos))
+        }
+    }
+    return self.la[i]
+}
+
+func (self *Parser) nextChar() (cc rune) {
+    if cc = self.peekChar(0); cc == _EOF {
+        return
+    }
+
+    /* advance the look-ahead buffer */
+    copy(self.la, self.la[1:])
+    self.la = self.la[:len(self.la) - 1]
+
+    /* update the current position */
+    if self.pos.Col++; cc == '\n' {
+        self.pos.Row++
+        self.pos.Col = 1
+    }
+
+    /* all done */
+    return
+}
+
+func (self *Parser) parseStr(sp Span) Value {
+    var sb strings.Builder
+    sb.WriteRune('"')
+
+    /* scan until the end of string */
+    for ch := self.nextChar(); ch != '"'; ch = self.nextChar() {
+        if ch == _EOF {
+            panic(self.errorEOF(sp, "string is not terminated"))
+        }
+
+        /* also copy the escaped character */
+        if sb.WriteRune(ch); ch == '\\' {
+            if ch = self.nextChar(); ch != _EOF {
+                sb.WriteRune(ch)
+            }
+        }
+    }
+
+    /* unquote the string */
+    sb.WriteRune('"')
+    ret, err := strconv.Unquote(sb.String())
+
+    /* check for errors */
+    if err != nil {
+        panic(self.errorAt(sp, "cannot parse string literal: " + err.Error()))
+    } else {
+        return String(ret)
+    }
+}
+
+func (self *Parser) parseCdr(sp Span) Value {
+    if ret := self.parseList(false); ret == nil {
+        return nil
+    } else {
+        self.sm[ret] = sp
+        return ret
+    }
+}
+
+func (self *Parser) parseList(topLevel bool) *List {
+    for p, q := (*List)(nil), (*List)(nil);; {
+        self.noSpace()
+        sp := self.pos
+
+        /* parse the next element */
+        if vv, ok := self.parseValue(topLevel); !ok || vv == Atom(")") {
+            return p
+        } else if vv != Atom(".") {
+            AppendValue(&p, &q, vv)
+            self.sm[q] = sp
+        } else if q == nil {
+            panic(self.error("ill-formed dotted list"))
+        } else if q.Cdr, ok = self.parseValue(false); !ok {
+            panic(self.error("cdr expression expected"))
+        } else if vv, ok = self.parseValue(false); !ok || vv != Atom(")") {
+            panic(self.error("')' expected"))
+        } else {
+            return p
+        }
+    }
+}
+
+func (self *Parser) parseChar(ch string) Char {
+    if _CharTab[ch] != 0 {
+        return Char(_CharTab[ch])
+    } else if chars := []rune(ch); len(chars) == 1 {
+        return Char(chars[0])
+    } else {
+        panic(self.error(`invalid character name #\` + ch))
+    }
+}
+
+func (self *Parser) parseValue(topLevel bool) (Value, bool) {
+    self.noSpace()
+    self.noEOF(topLevel)
+
+    /* the location of the value */
+    sp := self.pos
+    ch := self.nextChar()
+
+    /* check for simple cases */
+    switch ch {
+        case _EOF : return nil, false
+        case '\'' : return self.parseQuote("quote", sp)
+        case '`'  : return self.parseQuote("quasiquote", sp)
+        case ','  : return self.parseUnquote(sp)
+        case ')'  : return Atom(")"), true
+        case '"'  : return self.parseStr(sp), true
+        case '('  : return self.parseCdr(sp), true
+        case '#'  : return self.parseSharp(sp), true
+        default   : return self.parseSimple(ch, sp), true
+    }
+}
+
+func (self *Parser) parseQuote(name string, sp Span) (Value, bool) {
+    if vv, ok := self.parseValue(false); !ok {
+        return nil, false
+    } else {
+        ret := MakeList(Atom(name), vv)
+        self.sm[ret] = sp
+        return ret, true
+    }
+}
+
+func (self *Parser) parseUnquote(sp Span) (Value, bool) {
+    if self.peekChar(0) != '@' {
+        return self.parseQuote("unquote", sp)
+    } else {
+        self.nextChar()
+        return self.parseQuote("unquote-splicing", sp)
+    }
+}
+
+func (self *Parser) parseSharp(sp Span) Value {
+    if self.peekChar(0) != '(' {
+        return self.parseSimple('#', sp)
+    } else {
+        return self.parseVector(sp)
+    }
+}
+
+func (self *Parser) parseVector(sp Span) Value {
+    var ok bool
+    var ret []Value
+
+    /* parse the elements as a list */
+    self.nextChar()
+    vv := self.parseList(false)
+
+    /* vector literals must be proper lists */
+    for ok = true; ok && vv != nil; vv, ok = AsList(vv.Cdr) {
+        ret = append(ret, vv.Car)
+    }
+
+    /* check for list traversal */
+    if !ok {
+        panic(self.errorAt(sp, "ill-formed vector literal"))
+    } else {
+        return MakeVector(ret)
+    }
+}
+
+func (self *Parser) parseSimple(ch rune, sp Span) Value {
+    var sb strings.Builder
+    sb.WriteRune(ch)
+
+    /* character literals always take at least one character */
+    if ch == '#' && self.peekChar(0) == '\\' {
+        if sb.WriteRune(self.nextChar()); self.peekChar(0) != _EOF {
+            sb.WriteRune(self.nextChar())
+        }
+    }
+
+    /* scan until the next space or EOF */
+    for isAtomChar(self.peekChar(0)) {
+        sb.WriteRune(self.nextChar())
+    }
+
+    /* extract the token */
+    val := sb.String()
+
+    /* check for token types */
+    if val == "#t" {
+        return Bool(true)
+    } else if val == "#f" {
+        return Bool(false)
+    } else if strings.HasPrefix(val, `#\`) {
+        return self.parseChar(val[2:])
+    } else if iv, err := strconv.ParseInt(val, 0, 64); err == nil {
+        return Int(iv)
+    } else if bv, ok := new(big.Int).SetString(val, 0); ok {
+        return MakeInteger(bv)
+    } else if isZeroRational(val) {
+        panic(self.errorAt(sp, "division by zero in rational literal"))
+    } else if rv, ok := new(big.Rat).SetString(val); ok && strings.ContainsRune(val, '/') {
+        return MakeRational(rv)
+    } else if fv, err := strconv.ParseFloat(val, 64); err == nil {
+        return Float(fv)
+    } else if cv, err := strconv.ParseComplex(val, 128); err == nil {
+        return Complex(cv)
+    } else {
+        return Atom(val)
+    }
+}
+
+func (self *Parser) Next() (Value, bool) {
+    self.sm = make(SourceMap)
+    self.skipShebang()
+
+    /* parse the next top-level datum */
+    if vv, ok := self.parseValue(true); !ok {
+        return nil, false
+    } else if vv == Atom(")") {
+        panic(self.error("unexpected ')'"))
+    } else {
+        return vv, true
+    }
+}
+
+func (self *Parser) Parse() *List {
+    self.skipShebang()
+    return &List {
+        Car: Atom("begin"),
+        Cdr: self.parseList(true),
+    }
+}
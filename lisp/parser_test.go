@@ -0,0 +1,94 @@
+package lisp
+
+import (
+    `io`
+    `io/ioutil`
+    `strings`
+    `testing`
+
+    `github.com/stretchr/testify/require`
+)
+
+func TestParser(t *testing.T) {
+    src, err := ioutil.ReadFile("../mandelbrot.scm")
+    require.NoError(t, err)
+    ret := CreateParser(string(src)).Parse()
+    println(ret.String())
+}
+
+func TestParser_Comments(t *testing.T) {
+    src := `
+        ; line comment
+        (a b ; trailing comment
+           #| block #| nested |# comment |#
+           c #;(datum comment) d)
+    `
+    require.Equal(t, "(begin (a b c d))", CreateParser(src).Parse().String())
+    require.PanicsWithError(t, "<string>:2:9: syntax error: block comment is not terminated", func() {
+        CreateParser("(a)\n        #| unterminated").Parse()
+    })
+}
+
+func TestParser_Rational(t *testing.T) {
+    require.Equal(t, "(begin 1/2 -3/4 2)", CreateParser("2/4 -3/4 4/2").Parse().String())
+    require.PanicsWithError(t, "<string>:1:4: syntax error: division by zero in rational literal", func() {
+        CreateParser("(a 1/0)").Parse()
+    })
+    require.PanicsWithError(t, "<string>:1:1: syntax error: division by zero in rational literal", func() {
+        CreateParser("-3/00").Parse()
+    })
+}
+
+func TestParser_Stream(t *testing.T) {
+    rd, wr := io.Pipe()
+    ps := CreateStreamParser("<pipe>", rd)
+    go wr.Write([]byte("(display \"a \\\" b\") 'x\n"))
+    vv, ok := ps.Next()
+    require.True(t, ok)
+    require.Equal(t, `(display "a \" b")`, vv.String())
+    vv, ok = ps.Next()
+    require.True(t, ok)
+    require.Equal(t, "(quote x)", vv.String())
+    go wr.Close()
+    _, ok = ps.Next()
+    require.False(t, ok)
+}
+
+func TestParser_StreamSpans(t *testing.T) {
+    ps := CreateParser("(a (b))\n(c)")
+    v1, _ := ps.Next()
+    s1 := ps.Spans()
+    v2, _ := ps.Next()
+    s2 := ps.Spans()
+    require.Equal(t, 3, len(s1))
+    require.Equal(t, 1, len(s2))
+    sp, ok := s2.Locate(v2)
+    require.True(t, ok)
+    require.Equal(t, "<string>:2:1", sp.String())
+    _, ok = s2.Locate(v1)
+    require.False(t, ok)
+    _, err := CreateInterpreter().Eval("(define z 1)\n(let ((a 1) (b)) a)")
+    require.EqualError(t, err, "<string>:2:6: compile: malformed let construct: ((a 1) (b))")
+}
+
+func TestParser_Incomplete(t *testing.T) {
+    for _, src := range []string { "(a (b c)", "'", "\"abc", "#| comment", "#(1 2" } {
+        ps := CreateParser(src)
+        require.Error(t, CatchError(func() { for _, ok := ps.Next(); ok; _, ok = ps.Next() {} }))
+        require.True(t, ps.Incomplete(), src)
+    }
+    for _, src := range []string { "(a))", "(a . b c)", "#\\invalid" } {
+        ps := CreateParser(src)
+        require.Error(t, CatchError(func() { for _, ok := ps.Next(); ok; _, ok = ps.Next() {} }))
+        require.False(t, ps.Incomplete(), src)
+    }
+}
+
+func TestParser_Shebang(t *testing.T) {
+    require.Equal(t, "(begin (display 1))", CreateParser("#!/usr/bin/env simple-lisp\n(display 1)").Parse().String())
+    ps := CreateStreamParser("<test>", strings.NewReader("#!/usr/bin/env simple-lisp -e\n'x"))
+    vv, ok := ps.Next()
+    require.True(t, ok)
+    require.Equal(t, "(quote x)", vv.String())
+    require.Equal(t, "(begin (quote #!))", CreateParser("'#!").Parse().String())
+}
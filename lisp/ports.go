@@ -1,4 +1,4 @@
-package main
+package lisp
 
 import (
     `fmt`
@@ -15,11 +15,6 @@ type FileLike interface {
     Close() error
 }
 
-var PortStdout = &Port {
-    name: "<stdout>",
-    file: os.Stdout,
-}
-
 func CreatePort(name string, file FileLike) *Port {
     return &Port {
         name: name,
@@ -29,7 +24,7 @@ func CreatePort(name string, file FileLike) *Port {
 
 func OpenFileWritePort(fname string) *Port {
     if fp, err := os.OpenFile(fname, os.O_WRONLY | os.O_CREATE | os.O_TRUNC, 0666); err != nil {
-        panic(fmt.Sprintf("port: cannot open %s for write: %s", fname, err))
+        panic(MakeError(ErrIO, fmt.Sprintf("port: cannot open %s for write: %s", fname, err)))
     } else {
         return CreatePort(fname, CreateBufferedWriter(fp))
     }
@@ -41,7 +36,7 @@ func (self *Port) Close() {
 
 func (self *Port) Write(v []byte) {
     if _, err := self.file.Write(v); err != nil {
-        panic(fmt.Sprintf("port: write error to port %s: %s", self.name, err))
+        panic(MakeError(ErrIO, fmt.Sprintf("port: write error to port %s: %s", self.name, err)))
     }
 }
 
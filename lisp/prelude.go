@@ -0,0 +1,55 @@
+package lisp
+
+const _PreludeSource = `
+(define (dynamic-wind before thunk after)
+  (before)
+  (%wind-enter before after)
+  (let ((ret (thunk)))
+    (%wind-leave)
+    (after)
+    ret))
+
+(define-syntax %guard-aux
+  (syntax-rules (else =>)
+    ((_ reraise (else e1 e2 ...))
+     (begin e1 e2 ...))
+    ((_ reraise (test => proc) clause ...)
+     (let ((temp test))
+       (if temp (proc temp) (%guard-aux reraise clause ...))))
+    ((_ reraise (test) clause ...)
+     (let ((temp test))
+       (if temp temp (%guard-aux reraise clause ...))))
+    ((_ reraise (test e1 e2 ...) clause ...)
+     (if test (begin e1 e2 ...) (%guard-aux reraise clause ...)))
+    ((_ reraise)
+     reraise)))
+
+(define-syntax guard
+  (syntax-rules ()
+    ((_ (var clause ...) e1 e2 ...)
+     ((call/cc
+        (lambda (guard-k)
+          (with-exception-handler
+            (lambda (condition)
+              ((call/cc
+                 (lambda (handler-k)
+                   (guard-k
+                     (lambda ()
+                       (let ((var condition))
+                         (%guard-aux
+                           (handler-k (lambda () (raise-continuable condition)))
+                           clause ...))))))))
+            (lambda ()
+              (let ((ret (begin e1 e2 ...)))
+                (guard-k (lambda () ret)))))))))))
+`
+
+var (
+    _Prelude    Program
+    _PreludeEnv *Environ
+)
+
+func init() {
+    _PreludeEnv = CreateEnviron()
+    _Prelude = Compiler{Global: _PreludeEnv}.Compile(CreateNamedParser("<prelude>", _PreludeSource).Parse())
+}
@@ -0,0 +1,149 @@
+package lisp
+
+type _Primitive struct {
+    name string
+    mask uint32
+    proc func(Value, Value) Value
+    intr Value
+}
+
+var (
+    _PrimitiveTab  [256]*_Primitive
+    _PrimitiveBits uint
+)
+
+var _PrimitiveOps = map[Atom]OpCode {
+    "+"  : OP_add,
+    "-"  : OP_sub,
+    "*"  : OP_mul,
+    "/"  : OP_div,
+    "="  : OP_eq,
+    "<"  : OP_lt,
+    ">"  : OP_gt,
+    "<=" : OP_le,
+    ">=" : OP_ge,
+}
+
+func primitiveOf(name string) *_Primitive {
+    if op, ok := _PrimitiveOps[Atom(name)]; !ok {
+        return nil
+    } else {
+        return _PrimitiveTab[op]
+    }
+}
+
+func (self *Machine) primitive(fp *_Frame, pc int) {
+    y := stpop(&fp.st)
+    x := stpop(&fp.st)
+    cc := fp.link(pc)
+    pp := _PrimitiveTab[fp.code[pc].Op()]
+
+    /* the operator might be redefined, apply it as an ordinary proc if so */
+    if cc.Value == pp.intr {
+        fp.st = append(fp.st, pp.proc(x, y))
+    } else {
+        self.apply(cc.load(), []Value{x, y}, false)
+    }
+}
+
+/** Arithmetic Primitives **/
+
+func primitiveAdd(x Value, y Value) Value {
+    switch a := x.(type) {
+        case Int   : if b, ok := y.(Int)  ; ok { return numberAddInt(a, b) }
+        case Float : if b, ok := y.(Float); ok { return a + b }
+    }
+    return NumberAdd(x, y)
+}
+
+func primitiveSub(x Value, y Value) Value {
+    switch a := x.(type) {
+        case Int   : if b, ok := y.(Int)  ; ok { return numberSubInt(a, b) }
+        case Float : if b, ok := y.(Float); ok { return a - b }
+    }
+    return NumberSub(x, y)
+}
+
+func primitiveMul(x Value, y Value) Value {
+    switch a := x.(type) {
+        case Int   : if b, ok := y.(Int)  ; ok { return numberMulInt(a, b) }
+        case Float : if b, ok := y.(Float); ok { return a * b }
+    }
+    return NumberMul(x, y)
+}
+
+func primitiveDiv(x Value, y Value) Value {
+    if a, ok := x.(Float); ok {
+        if b, ok := y.(Float); ok {
+            return a / b
+        }
+    }
+    return NumberDiv(x, y)
+}
+
+/** Comparison Primitives **/
+
+func primitiveEq(x Value, y Value) Value {
+    switch a := x.(type) {
+        case Int   : if b, ok := y.(Int)  ; ok { return Bool(a == b) }
+        case Float : if b, ok := y.(Float); ok { return Bool(a == b) }
+    }
+    return Bool(NumberCompareEq(x, y))
+}
+
+func primitiveLt(x Value, y Value) Value {
+    switch a := x.(type) {
+        case Int   : if b, ok := y.(Int)  ; ok { return Bool(a < b) }
+        case Float : if b, ok := y.(Float); ok { return Bool(a < b) }
+    }
+    return Bool(NumberCompareLt(x, y))
+}
+
+func primitiveGt(x Value, y Value) Value {
+    switch a := x.(type) {
+        case Int   : if b, ok := y.(Int)  ; ok { return Bool(a > b) }
+        case Float : if b, ok := y.(Float); ok { return Bool(a > b) }
+    }
+    return Bool(NumberCompareGt(x, y))
+}
+
+func primitiveLe(x Value, y Value) Value {
+    switch a := x.(type) {
+        case Int   : if b, ok := y.(Int)  ; ok { return Bool(a <= b) }
+        case Float : if b, ok := y.(Float); ok { return Bool(a <= b) }
+    }
+    return Bool(NumberCompareLte(x, y))
+}
+
+func primitiveGe(x Value, y Value) Value {
+    switch a := x.(type) {
+        case Int   : if b, ok := y.(Int)  ; ok { return Bool(a >= b) }
+        case Float : if b, ok := y.(Float); ok { return Bool(a >= b) }
+    }
+    return Bool(NumberCompareGte(x, y))
+}
+
+func RegisterPrimitive(op OpCode, name string, proc func(Value, Value) Value) {
+    if _PrimitiveTab[op] != nil {
+        panic("registry: duplicated primitive: " + name)
+    } else if _PrimitiveBits >= 32 {
+        panic("registry: too many primitives: " + name)
+    } else if fn, ok := intrinsicsTab[name]; !ok {
+        panic("registry: primitive does not have an intrinsic: " + name)
+    } else {
+        _PrimitiveTab[op] = &_Primitive { name: name, mask: 1 << _PrimitiveBits, proc: proc, intr: fn }
+        _PrimitiveBits++
+    }
+}
+
+func init() {
+    RegisterPrimitive(OP_add, "+", primitiveAdd)
+    RegisterPrimitive(OP_sub, "-", primitiveSub)
+    RegisterPrimitive(OP_mul, "*", primitiveMul)
+    RegisterPrimitive(OP_div, "/", primitiveDiv)
+    RegisterPrimitive(OP_eq, "=", primitiveEq)
+    RegisterPrimitive(OP_lt, "<", primitiveLt)
+    RegisterPrimitive(OP_gt, ">", primitiveGt)
+    RegisterPrimitive(OP_le, "<=", primitiveLe)
+    RegisterPrimitive(OP_ge, ">=", primitiveGe)
+}
@@ -0,0 +1,112 @@
+package lisp
+
+import (
+    `fmt`
+    `strings`
+)
+
+type Proc struct {
+    Name  string
+    Code  Program
+    Args  []string
+    Rest  string
+    Slots int
+}
+
+func (self *Proc) String() string {
+    buf := []string { self.Name }
+    buf = append(buf, self.Args...)
+
+    /* variadic procs have a dotted argument list */
+    if self.Rest != "" {
+        buf = append(buf, ".", self.Rest)
+    }
+
+    /* build the proc signature */
+    return fmt.Sprintf("#[proc (%s)]", strings.Join(buf, " "))
+}
+
+func (self *Proc) Disassemble(lines bool) string {
+    var pp *Proc
+    var vv []*Proc
+    var dis string
+    var ret []string
+
+    /* procedure queue */
+    pq := []*Proc { self }
+
+    /* BFS the queue */
+    for len(pq) != 0 {
+        pp = pq[0]
+        pq = pq[1:]
+
+        /* procedure name */
+        ret = append(ret, fmt.Sprintf(
+            "Procedure %q:\n",
+            pp.Name,
+        ))
+
+        /* disassemble the procedure */
+        dis, vv = pp.Code.Disasm(lines)
+        ret, pq = append(ret, dis), append(pq, vv...)
+    }
+
+    /* join the entire program */
+    return strings.Join(ret, "\n")
+}
+
+func (self *Proc) IsVariadic() bool {
+    return self.Rest != ""
+}
+
+func (self *Proc) IsIdentity() bool {
+    return true
+}
+
+func (self *Proc) Load(scope *Scope, vars *Locals) LoadedProc {
+    return self.load(scope, vars, newLinks(self.Code))
+}
+
+func (self *Proc) load(scope *Scope, vars *Locals, links *_Links) LoadedProc {
+    return LoadedProc {
+        Proc   : self,
+        Scope  : scope,
+        Locals : vars,
+        links  : links,
+    }
+}
+
+type LoadedProc struct {
+    *Proc
+    *Scope
+    *Locals
+    links *_Links
+}
+
+func (self LoadedProc) Call(args []Value) Value {
+    return Apply(self, args)
+}
+
+type TailCall struct {
+    Proc Value
+    Args []Value
+}
+
+func MakeTailCall(proc Value, args []Value) *TailCall {
+    return &TailCall {
+        Proc: proc,
+        Args: args,
+    }
+}
+
+func (self *TailCall) String() string {
+    return fmt.Sprintf("#[tail-call %s]", AsString(self.Proc))
+}
+
+func (self *TailCall) IsIdentity() bool {
+    return true
+}
+
+func (self *TailCall) Resolve() Value {
+    return Apply(self.Proc, self.Args)
+}
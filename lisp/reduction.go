@@ -1,4 +1,4 @@
-package main
+package lisp
 
 func reduceSequential(vals []Value, iter func(Value, Value) Value) (ret Value) {
     ret = vals[0]
@@ -0,0 +1,183 @@
+package lisp
+
+import (
+    `io`
+    `os`
+    `strings`
+)
+
+const (
+    ReplPrompt       = "> "
+    ReplPromptMore   = "... "
+    ReplSourceName   = "<repl>"
+)
+
+var _ReplKeywords = []string {
+    "and",
+    "begin",
+    "define",
+    "define-syntax",
+    "do",
+    "if",
+    "lambda",
+    "let",
+    "let*",
+    "let-syntax",
+    "letrec",
+    "letrec-syntax",
+    "or",
+    "quasiquote",
+    "quote",
+    "set!",
+    "syntax-rules",
+    "unquote",
+    "unquote-splicing",
+}
+
+type REPL struct {
+    ed *LineEditor
+    it *Interpreter
+}
+
+func CreateREPL(it *Interpreter) *REPL {
+    ret := &REPL {
+        ed : CreateLineEditor(os.Stdin, it.Stdout),
+        it : it,
+    }
+
+    /* complete names that are bound in the REPL */
+    ret.ed.Complete = ret.complete
+    return ret
+}
+
+func (self *REPL) complete(prefix string) []string {
+    ret := []string(nil)
+    set := make(map[string]bool)
+
+    /* special forms */
+    for _, v := range _ReplKeywords {
+        set[v] = true
+    }
+
+    /* global variables */
+    for k, v := range self.it.scope.defs {
+        if v.bound {
+            set[k] = true
+        }
+    }
+
+    /* global macros */
+    for k, v := range self.it.env.defs {
+        if v != nil {
+            set[string(k)] = true
+        }
+    }
+
+    /* find all the matching names */
+    for k := range set {
+        if strings.HasPrefix(k, prefix) {
+            ret = append(ret, k)
+        }
+    }
+
+    /* sort the candidates, the list is short so insertion sort is sufficient */
+    for i := 1; i < len(ret); i++ {
+        for j := i; j > 0 && ret[j] < ret[j - 1]; j-- {
+            ret[j], ret[j - 1] = ret[j - 1], ret[j]
+        }
+    }
+
+    /* all done */
+    return ret
+}
+
+func (self *REPL) parse(src string) (ret []Value, sm SourceMap, ps *Parser, err error) {
+    sm = make(SourceMap)
+    ps = CreateNamedParser(ReplSourceName, src)
+
+    /* the input is short, keep the spans of all the forms until they are evaluated */
+    err = CatchError(func() {
+        for vv, ok := ps.Next(); ok; vv, ok = ps.Next() {
+            for k, v := range ps.Spans() { sm[k] = v }
+            ret = append(ret, vv)
+        }
+    })
+    return
+}
+
+func (self *REPL) eval(sm SourceMap, vals []Value) {
+    for _, vv := range vals {
+        var rv Value
+        var cc = self.it.compiler(sm)
+
+        /* compile and evaluate the form, errors are unwound back to the top level */
+        err := self.it.protect(func() {
+            rv = self.it.evaluate(cc.Compile(MakeList(Atom("begin"), vv)))
+        })
+
+        /* stop at the first error */
+        if err != nil {
+            println(err.Error())
+            return
+        }
+
+        /* unspecified values are not shown */
+        if _, ok := rv.(Unspecified); !ok {
+            self.it.Stdout.Write([]byte(AsString(rv) + "\n"))
+        }
+    }
+}
+
+func (self *REPL) Run() error {
+    var buf strings.Builder
+    var prompt = ReplPrompt
+
+    /* read-eval-print loop */
+    for {
+        line, err := self.ed.ReadLine(prompt)
+
+        /* interruption discards the partial input */
+        if err == ErrInterrupted {
+            buf.Reset()
+            prompt = ReplPrompt
+            continue
+        }
+
+        /* EOF terminates the REPL, reporting the incomplete input if any */
+        if err == io.EOF {
+            if buf.Len() != 0 {
+                _, _, _, err = self.parse(buf.String())
+                println(err.Error())
+            }
+            return nil
+        } else if err != nil {
+            return err
+        }
+
+        /* accumulate lines until the forms are balanced */
+        buf.WriteString(line)
+        buf.WriteByte('\n')
+        vals, sm, ps, err := self.parse(buf.String())
+
+        /* wait for more lines if the input is incomplete */
+        if err != nil && ps.Incomplete() {
+            prompt = ReplPromptMore
+            continue
+        }
+
+        /* the entire input is recorded as one history entry */
+        src := buf.String()
+        self.ed.AddHistory(strings.ReplaceAll(strings.TrimSpace(src), "\n", " "))
+
+        /* reset the input buffer */
+        buf.Reset()
+        prompt = ReplPrompt
+
+        /* evaluate the forms if the syntax is correct */
+        if err != nil {
+            println(err.Error())
+        } else {
+            self.eval(sm, vals)
+        }
+    }
+}
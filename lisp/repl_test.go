@@ -0,0 +1,51 @@
+package lisp
+
+import (
+    `bytes`
+    `testing`
+
+    `github.com/stretchr/testify/require`
+)
+
+type _TestOutput struct {
+    bytes.Buffer
+}
+
+func (self *_TestOutput) Close() error {
+    return nil
+}
+
+func TestREPL_Print(t *testing.T) {
+    it := CreateInterpreter()
+    out := new(_TestOutput)
+    it.Stdout = CreatePort("<test>", out)
+    repl := CreateREPL(it)
+    vals, sm, _, err := repl.parse("(define x 1) (set! x 2) '() (list x) (if #f #f) (display x) (newline) (vector-fill! (make-vector 1) 0)")
+    require.NoError(t, err)
+    repl.eval(sm, vals)
+    require.Equal(t, "()\n(2)\n2\n", out.String())
+}
+
+func TestREPL_Complete(t *testing.T) {
+    repl := CreateREPL(CreateInterpreter())
+    vals, sm, _, err := repl.parse("(define vector-foo 1) (define-syntax vector-far (syntax-rules () ((_) 1)))")
+    require.NoError(t, err)
+    repl.eval(sm, vals)
+    require.Equal(t, []string { "vector-far", "vector-fill!", "vector-fold", "vector-foo", "vector-for-each" }, repl.complete("vector-f"))
+    require.Equal(t, []string { "letrec", "letrec-syntax" }, repl.complete("letr"))
+    require.Empty(t, repl.complete("no-such-name"))
+}
+
+func TestREPL_Recover(t *testing.T) {
+    repl := CreateREPL(CreateInterpreter())
+    vals, sm, _, err := repl.parse(`
+        (define n 0)
+        (dynamic-wind (lambda () #t) (lambda () (car 1)) (lambda () (set! n (+ n 1))))
+    `)
+    require.NoError(t, err)
+    repl.eval(sm, vals)
+    require.Nil(t, repl.it.winds)
+    require.Nil(t, repl.it.handlers)
+    v, _ := repl.it.Lookup("n")
+    require.Equal(t, Int(1), v)
+}
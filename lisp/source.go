@@ -0,0 +1,34 @@
+package lisp
+
+import (
+    `fmt`
+)
+
+type Span struct {
+    File string
+    Row  int
+    Col  int
+}
+
+func (self Span) IsValid() bool {
+    return self.Row != 0
+}
+
+func (self Span) String() string {
+    if self.File == "" && self.Col == 0 {
+        return fmt.Sprintf("line %d", self.Row)
+    } else {
+        return fmt.Sprintf("%s:%d:%d", self.File, self.Row, self.Col)
+    }
+}
+
+type SourceMap map[*List]Span
+
+func (self SourceMap) Locate(v Value) (Span, bool) {
+    if vv, ok := v.(*List); !ok || vv == nil {
+        return Span{}, false
+    } else {
+        sp, ok := self[vv]
+        return sp, ok
+    }
+}
@@ -0,0 +1,687 @@
+package lisp
+
+import (
+    `fmt`
+)
+
+type Environ struct {
+    prev  *Environ
+    ids   *IdGen
+    defs  map[Atom]*Macro
+    refs  map[Atom]_Alias
+    slots map[Atom]int
+    syntax bool
+}
+
+type _Alias struct {
+    name Atom
+    decl *Environ
+}
+
+type Macro struct {
+    Name     string
+    decl     *Environ
+    rules    [][2]Value
+    literals []Atom
+    ellipsis Atom
+}
+
+func (self *Macro) mark(refs map[Atom]_Alias, keep map[Atom]bool) {
+    markAliases(refs, keep, self.ellipsis)
+    for _, v := range self.literals {
+        markAliases(refs, keep, v)
+    }
+    for _, v := range self.rules {
+        markAliases(refs, keep, v[0])
+        markAliases(refs, keep, v[1])
+    }
+}
+
+func markAliases(refs map[Atom]_Alias, keep map[Atom]bool, v Value) {
+    switch vv := v.(type) {
+        case Atom: {
+            if al, ok := refs[vv]; ok && !keep[vv] {
+                keep[vv] = true
+                markAliases(refs, keep, al.name)
+            }
+        }
+
+        /* mark all the elements and the tail */
+        case *List: {
+            elem, tail := splitList(vv)
+            markAliases(refs, keep, tail)
+            for _, x := range elem {
+                markAliases(refs, keep, x)
+            }
+        }
+
+        /* same for vectors */
+        case *Vector: {
+            for _, x := range vv.Elems {
+                markAliases(refs, keep, x)
+            }
+        }
+    }
+}
+
+type _Binding struct {
+    val Value
+    seq []*_Binding
+}
+
+type _Bindings map[Atom]*_Binding
+
+func CreateEnviron() *Environ {
+    return createEnviron(new(IdGen))
+}
+
+func createEnviron(ids *IdGen) (ret *Environ) {
+    ret = &Environ {
+        ids   : ids,
+        defs  : make(map[Atom]*Macro),
+        refs  : make(map[Atom]_Alias),
+        slots : make(map[Atom]int),
+    }
+
+    /* macros defined by the prelude are visible to every program */
+    if _PreludeEnv != nil {
+        for k, v := range _PreludeEnv.defs {
+            ret.defs[k] = v
+        }
+    }
+
+    /* all done */
+    return
+}
+
+func (self *Environ) Derive() *Environ {
+    return &Environ {
+        prev  : self,
+        ids   : self.ids,
+        defs  : make(map[Atom]*Macro),
+        refs  : self.refs,
+        slots : make(map[Atom]int),
+    }
+}
+
+func (self *Environ) DeriveSyntax() *Environ {
+    ret := self.Derive()
+    ret.syntax = true
+    return ret
+}
+
+func (self *Environ) Scope() *Environ {
+    for self.syntax {
+        self = self.prev
+    }
+    return self
+}
+
+func (self *Environ) IsGlobal() bool {
+    return self.prev == nil
+}
+
+func (self *Environ) Slots() int {
+    return len(self.slots)
+}
+
+func (self *Environ) Bind(name Atom) {
+    if _, ok := self.slots[name]; !ok {
+        self.slots[name] = len(self.slots)
+    }
+    self.defs[name] = nil
+}
+
+func (self *Environ) BindMacro(name Atom, macro *Macro) {
+    self.defs[name] = macro
+}
+
+func (self *Environ) Unbind(name Atom) {
+    delete(self.defs, name)
+}
+
+func (self *Environ) Alias(name Atom, decl *Environ) Atom {
+    ret := Atom(fmt.Sprintf("#[%s %d]", self.Strip(name), self.ids.Next()))
+    self.refs[ret] = _Alias { name: name, decl: decl }
+    return ret
+}
+
+func (self *Environ) Sweep() {
+    if len(self.refs) == 0 {
+        return
+    }
+
+    /* aliases are only reachable from the templates of the global macros once the program is compiled */
+    keep := make(map[Atom]bool)
+    for _, mm := range self.defs {
+        if mm != nil {
+            mm.mark(self.refs, keep)
+        }
+    }
+
+    /* drop all the other aliases */
+    for k := range self.refs {
+        if !keep[k] {
+            delete(self.refs, k)
+        }
+    }
+}
+
+func (self *Environ) Resolve(name Atom) (*Environ, Atom, *Macro) {
+    for p := self; p != nil; p = p.prev {
+        if mm, ok := p.defs[name]; ok {
+            return p, name, mm
+        }
+    }
+
+    /* aliases are resolved in the environment where the macro is declared */
+    if al, ok := self.refs[name]; ok {
+        return al.decl.Resolve(al.name)
+    } else {
+        return nil, name, nil
+    }
+}
+
+func (self *Environ) Address(env *Environ, name Atom) (depth int, index int) {
+    var ok bool
+    var p  *Environ
+
+    /* syntax environments does not have runtime frames */
+    for p = self; p != nil && p != env; p = p.prev {
+        if !p.syntax {
+            depth++
+        }
+    }
+
+    /* the variable must have a slot */
+    if p == nil {
+        panic("fatal: variable is not in the lexical scope: " + string(name))
+    } else if index, ok = env.slots[name]; !ok {
+        panic("fatal: variable does not have a slot: " + string(name))
+    } else {
+        return
+    }
+}
+
+func (self *Environ) Keyword(name Atom) (Atom, bool) {
+    if env, at, mm := self.Resolve(name); env != nil || mm != nil {
+        return "", false
+    } else {
+        return at, true
+    }
+}
+
+func (self *Environ) IsSame(a Atom, env *Environ, b Atom) bool {
+    ea, na, _ := self.Resolve(a)
+    eb, nb, _ := env.Resolve(b)
+    return ea == eb && na == nb
+}
+
+func (self *Environ) Strip(v Value) Value {
+    switch vv := v.(type) {
+        case Atom    : return self.stripAtom(vv)
+        case *List   : return self.stripList(vv)
+        case *Vector : return self.stripVector(vv)
+        default      : return v
+    }
+}
+
+func (self *Environ) stripAtom(v Atom) Atom {
+    for {
+        if al, ok := self.refs[v]; !ok {
+            return v
+        } else {
+            v = al.name
+        }
+    }
+}
+
+func (self *Environ) stripList(v *List) Value {
+    if v == nil {
+        return nil
+    }
+
+    /* only copy the list when needed */
+    car := self.Strip(v.Car)
+    cdr := self.Strip(v.Cdr)
+
+    /* check for modifications */
+    if car == v.Car && cdr == v.Cdr {
+        return v
+    } else {
+        return MakePair(car, cdr)
+    }
+}
+
+func (self *Environ) stripVector(v *Vector) Value {
+    ok := false
+    ret := make([]Value, len(v.Elems))
+
+    /* strip every element */
+    for i, x := range v.Elems {
+        ret[i] = self.Strip(x)
+        ok = ok || ret[i] != x
+    }
+
+    /* only copy the vector when needed */
+    if !ok {
+        return v
+    } else {
+        return MakeVector(ret)
+    }
+}
+
+/** Macro Transformer Parsing **/
+
+func (self Compiler) compileSyntaxRules(name Atom, v Value, decl *Environ) *Macro {
+    var ok bool
+    var at Atom
+    var vv *List
+    var rr *List
+
+    /* transformer must be a list */
+    if vv, ok = v.(*List); !ok || vv == nil {
+        panic(self.error("malformed syntax transformer", v))
+    }
+
+    /* only `syntax-rules` is supported */
+    if at, ok = vv.Car.(Atom); !ok || !self.isKeyword(at, "syntax-rules") {
+        panic(self.error("unsupported syntax transformer", vv))
+    }
+
+    /* the macro object */
+    ret := &Macro {
+        Name     : string(self.env.Strip(name).(Atom)),
+        decl     : decl,
+        ellipsis : "...",
+    }
+
+    /* extract the literal list */
+    if vv, ok = vv.Cdr.(*List); !ok || vv == nil {
+        panic(self.error("malformed syntax-rules", v))
+    }
+
+    /* check for custom ellipsis */
+    if at, ok = vv.Car.(Atom); ok {
+        ret.ellipsis = self.env.stripAtom(at)
+        vv, ok = vv.Cdr.(*List)
+    } else {
+        ok = true
+    }
+
+    /* extract the rules */
+    if !ok || vv == nil             { panic(self.error("malformed syntax-rules", v)) }
+    if rr, ok = AsList(vv.Cdr); !ok { panic(self.error("malformed syntax-rules", v)) }
+
+    /* parse the literals */
+    for _, lit := range self.identList(vv.Car, "syntax-rules literal", v) {
+        ret.literals = append(ret.literals, lit)
+    }
+
+    /* parse every rule */
+    for ; ok && rr != nil; rr, ok = AsList(rr.Cdr) {
+        var pat *List
+        var rule *List
+
+        /* each rule must be a list of exact 2 elements, and the pattern must be a list */
+        if rule, ok = rr.Car.(*List)   ; !ok || rule == nil { panic(self.error("malformed syntax rule", rr.Car)) }
+        if pat, ok = rule.Car.(*List)  ; !ok || pat == nil  { panic(self.error("malformed syntax rule", rr.Car)) }
+        if rule, ok = rule.Cdr.(*List) ; !ok || rule == nil { panic(self.error("malformed syntax rule", rr.Car)) }
+        if rule.Cdr != nil                                  { panic(self.error("malformed syntax rule", rr.Car)) }
+
+        /* the first element of the pattern is always ignored */
+        ret.rules = append(ret.rules, [2]Value {
+            pat.Cdr,
+            rule.Car,
+        })
+    }
+
+    /* check for list traversal */
+    if !ok {
+        panic(self.error("malformed syntax-rules", v))
+    } else {
+        return ret
+    }
+}
+
+func (self Compiler) identList(v Value, what string, form Value) (ret []Atom) {
+    vv, ok := AsList(v)
+
+    /* every element must be an identifier */
+    for ; ok && vv != nil; vv, ok = AsList(vv.Cdr) {
+        if at, isa := vv.Car.(Atom); !isa {
+            panic(self.error(fmt.Sprintf("%s must be an identifier", what), form))
+        } else {
+            ret = append(ret, at)
+        }
+    }
+
+    /* must be a proper list */
+    if !ok {
+        panic(self.error(fmt.Sprintf("malformed %s list", what), form))
+    } else {
+        return
+    }
+}
+
+/** Macro Expansion **/
+
+func (self Compiler) expandMacro(macro *Macro, v *List) Value {
+    for _, rule := range macro.rules {
+        bind := make(_Bindings)
+        refs := make(map[Atom]Atom)
+
+        /* try to match the pattern */
+        if self.matchPattern(macro, rule[0], v.Cdr, bind) {
+            return self.expandTemplate(macro, rule[1], bind, refs, false)
+        }
+    }
+
+    /* none of the rules matches */
+    panic(self.error(fmt.Sprintf("no matching syntax rule for `%s`", macro.Name), v))
+}
+
+func (self Compiler) isEllipsis(macro *Macro, v Value) bool {
+    at, ok := v.(Atom)
+    return ok && self.env.stripAtom(at) == macro.ellipsis
+}
+
+func (self Compiler) isLiteral(macro *Macro, v Atom) bool {
+    for _, lit := range macro.literals {
+        if lit == v {
+            return true
+        }
+    }
+    return false
+}
+
+func (self Compiler) matchPattern(macro *Macro, pat Value, v Value, bind _Bindings) bool {
+    switch pv := pat.(type) {
+        case Atom    : return self.matchIdent(macro, pv, v, bind)
+        case *List   : return self.matchList(macro, pv, v, bind)
+        case *Vector : return self.matchVector(macro, pv, v, bind)
+        default      : return isSameDatum(pat, v)
+    }
+}
+
+func (self Compiler) matchIdent(macro *Macro, pat Atom, v Value, bind _Bindings) bool {
+    if self.env.stripAtom(pat) == "_" {
+        return true
+    } else if !self.isLiteral(macro, pat) {
+        bind[pat] = &_Binding { val: v }
+        return true
+    } else if at, ok := v.(Atom); !ok {
+        return false
+    } else {
+        return self.env.IsSame(at, macro.decl, pat)
+    }
+}
+
+func (self Compiler) matchVector(macro *Macro, pat *Vector, v Value, bind _Bindings) bool {
+    if vv, ok := v.(*Vector); !ok {
+        return false
+    } else {
+        return self.matchList(macro, MakeList(pat.Elems...), MakeList(vv.Elems...), bind)
+    }
+}
+
+func (self Compiler) matchList(macro *Macro, pat *List, v Value, bind _Bindings) bool {
+    nb := 0
+    pe, pt := splitList(pat)
+    ve, vt := splitList(v)
+
+    /* find the ellipsis */
+    for nb < len(pe) && !self.isEllipsis(macro, pe[nb]) {
+        nb++
+    }
+
+    /* matching without ellipsis */
+    if nb == len(pe) {
+        return self.matchSeq(macro, pe, ve, pt, vt, bind)
+    }
+
+    /* the ellipsis must follow some pattern */
+    if nb == 0 {
+        panic(self.error("misplaced ellipsis in syntax pattern"))
+    }
+
+    /* elements before and after the repeated one */
+    rep := pe[nb - 1]
+    pre := pe[:nb - 1]
+    post := pe[nb + 1:]
+
+    /* check for the minimal number of elements */
+    if len(ve) < len(pre) + len(post) {
+        return false
+    }
+
+    /* match the leading elements */
+    if !self.matchSeq(macro, pre, ve[:len(pre)], nil, nil, bind) {
+        return false
+    }
+
+    /* match the repeated elements */
+    if !self.matchRepeat(macro, rep, ve[len(pre):len(ve) - len(post)], bind) {
+        return false
+    }
+
+    /* match the trailing elements */
+    return self.matchSeq(macro, post, ve[len(ve) - len(post):], pt, vt, bind)
+}
+
+func (self Compiler) matchSeq(macro *Macro, pat []Value, v []Value, pt Value, vt Value, bind _Bindings) bool {
+    if len(pat) > len(v) || (pt == nil && len(pat) != len(v)) {
+        return false
+    }
+
+    /* match each element */
+    for i, pv := range pat {
+        if !self.matchPattern(macro, pv, v[i], bind) {
+            return false
+        }
+    }
+
+    /* the remaining elements are matched against the tail */
+    if pt == nil {
+        return vt == nil
+    } else {
+        return self.matchPattern(macro, pt, rebuildList(v[len(pat):], vt), bind)
+    }
+}
+
+func (self Compiler) matchRepeat(macro *Macro, pat Value, v []Value, bind _Bindings) bool {
+    var ok bool
+    var vars []Atom
+
+    /* all the pattern variables are bound to sequences */
+    for _, at := range self.patternVars(macro, pat, nil) {
+        vars = append(vars, at)
+        bind[at] = &_Binding { seq: []*_Binding{} }
+    }
+
+    /* match every element */
+    for _, vv := range v {
+        sub := make(_Bindings)
+
+        /* match one element */
+        if ok = self.matchPattern(macro, pat, vv, sub); !ok {
+            return false
+        }
+
+        /* append to sequences */
+        for _, at := range vars {
+            bind[at].seq = append(bind[at].seq, sub[at])
+        }
+    }
+
+    /* all done */
+    return true
+}
+
+func (self Compiler) patternVars(macro *Macro, pat Value, ret []Atom) []Atom {
+    switch pv := pat.(type) {
+        default: {
+            return ret
+        }
+
+        /* identifiers other than literals, wildcards or ellipsis are pattern variables */
+        case Atom: {
+            if self.isEllipsis(macro, pv) || self.isLiteral(macro, pv) || self.env.stripAtom(pv) == "_" {
+                return ret
+            } else {
+                return append(ret, pv)
+            }
+        }
+
+        /* scan through lists */
+        case *List: {
+            if pv == nil {
+                return ret
+            } else {
+                return self.patternVars(macro, pv.Cdr, self.patternVars(macro, pv.Car, ret))
+            }
+        }
+
+        /* scan through vectors */
+        case *Vector: {
+            for _, v := range pv.Elems { ret = self.patternVars(macro, v, ret) }
+            return ret
+        }
+    }
+}
+
+func (self Compiler) expandTemplate(macro *Macro, tmpl Value, bind _Bindings, refs map[Atom]Atom, esc bool) Value {
+    switch tv := tmpl.(type) {
+        case Atom    : return self.expandIdent(macro, tv, bind, refs)
+        case *List   : return self.expandList(macro, tv, bind, refs, esc)
+        case *Vector : return self.expandVector(macro, tv, bind, refs, esc)
+        default      : return tmpl
+    }
+}
+
+func (self Compiler) expandVector(macro *Macro, tmpl *Vector, bind _Bindings, refs map[Atom]Atom, esc bool) Value {
+    ret, _ := splitList(self.expandList(macro, MakeList(tmpl.Elems...), bind, refs, esc))
+    return MakeVector(ret)
+}
+
+func (self Compiler) expandIdent(macro *Macro, tmpl Atom, bind _Bindings, refs map[Atom]Atom) Value {
+    if vv, ok := bind[tmpl]; ok && vv.seq == nil {
+        return vv.val
+    } else if ok {
+        panic(self.error(fmt.Sprintf("pattern variable `%s` is used without ellipsis in `%s`", tmpl, macro.Name)))
+    } else if at, ok := refs[tmpl]; ok {
+        return at
+    } else {
+        refs[tmpl] = self.env.Alias(tmpl, macro.decl)
+        return refs[tmpl]
+    }
+}
+
+func (self Compiler) expandList(macro *Macro, tmpl *List, bind _Bindings, refs map[Atom]Atom, esc bool) Value {
+    var ret []Value
+    te, tt := splitList(tmpl)
+
+    /* escaped ellipsis: (... <template>) */
+    if !esc && len(te) != 0 && self.isEllipsis(macro, te[0]) {
+        if len(te) != 2 || tt != nil {
+            panic(self.error("malformed ellipsis escape in syntax template", tmpl))
+        } else {
+            return self.expandTemplate(macro, te[1], bind, refs, true)
+        }
+    }
+
+    /* expand every element */
+    for i := 0; i < len(te); i++ {
+        nb := 0
+        vv := te[i]
+
+        /* count the following ellipsis */
+        for !esc && i + 1 < len(te) && self.isEllipsis(macro, te[i + 1]) {
+            i++
+            nb++
+        }
+
+        /* expand the element */
+        if nb == 0 {
+            ret = append(ret, self.expandTemplate(macro, vv, bind, refs, esc))
+        } else {
+            ret = append(ret, self.expandRepeat(macro, vv, nb, bind, refs)...)
+        }
+    }
+
+    /* expand the template tail */
+    return rebuildList(ret, self.expandTemplate(macro, tt, bind, refs, esc))
+}
+
+func (self Compiler) expandRepeat(macro *Macro, tmpl Value, depth int, bind _Bindings, refs map[Atom]Atom) (ret []Value) {
+    nb := -1
+    vars := []Atom(nil)
+
+    /* find all the sequence variables */
+    for _, at := range self.patternVars(macro, tmpl, nil) {
+        if vv, ok := bind[at]; ok && vv.seq != nil {
+            vars = append(vars, at)
+        }
+    }
+
+    /* must have at least one sequence variable */
+    if len(vars) == 0 {
+        panic(self.error(fmt.Sprintf("no pattern variables before ellipsis in `%s`", macro.Name)))
+    }
+
+    /* all sequences must have the same length */
+    for _, at := range vars {
+        if nb < 0 {
+            nb = len(bind[at].seq)
+        } else if nb != len(bind[at].seq) {
+            panic(self.error(fmt.Sprintf("incompatible ellipsis match counts in `%s`", macro.Name)))
+        }
+    }
+
+    /* expand every iteration */
+    for i := 0; i < nb; i++ {
+        sub := make(_Bindings, len(bind))
+        for k, v := range bind { sub[k] = v }
+        for _, at := range vars { sub[at] = bind[at].seq[i] }
+
+        /* expand one more level if needed */
+        if depth == 1 {
+            ret = append(ret, self.expandTemplate(macro, tmpl, sub, refs, false))
+        } else {
+            ret = append(ret, self.expandRepeat(macro, tmpl, depth - 1, sub, refs)...)
+        }
+    }
+
+    /* all done */
+    return
+}
+
+/** Macro Helpers **/
+
+func isSameDatum(a Value, b Value) bool {
+    if x, ok := a.(Numerical); !ok {
+        return a == b
+    } else if y, ok := b.(Numerical); !ok {
+        return false
+    } else {
+        return (x.Kind() < NumFloat) == (y.Kind() < NumFloat) && NumberCompareEq(x, y)
+    }
+}
+
+func splitList(v Value) (ret []Value, tail Value) {
+    for vv, ok := v.(*List); ok && vv != nil; vv, ok = v.(*List) {
+        v, ret = vv.Cdr, append(ret, vv.Car)
+    }
+
+    /* normalize the empty list */
+    if vv, ok := v.(*List); ok && vv == nil {
+        return ret, nil
+    } else {
+        return ret, v
+    }
+}
+
+func rebuildList(vals []Value, tail Value) Value {
+    for i := len(vals) - 1; i >= 0; i-- { tail = MakePair(vals[i], tail) }
+    return tail
+}
@@ -1,4 +1,4 @@
-package main
+package lisp
 
 const (
     MaxTracing = 1000000
@@ -25,7 +25,7 @@ func OptimizeTailCall(p Program) {
     for i := 0; i < len(p); i++ {
         if p[i].Op() == OP_apply {
             if isTailCall(p, i + 1) {
-                p[i].u0 = uint32(OP_tailcall)
+                p[i].setOp(OP_tailcall)
             }
         }
     }
@@ -0,0 +1,281 @@
+package lisp
+
+import (
+    `bufio`
+    `io`
+    `os`
+    `strconv`
+    `strings`
+)
+
+const (
+    _KEY_CTRL_A    = 0x01
+    _KEY_CTRL_B    = 0x02
+    _KEY_CTRL_C    = 0x03
+    _KEY_CTRL_D    = 0x04
+    _KEY_CTRL_E    = 0x05
+    _KEY_CTRL_F    = 0x06
+    _KEY_CTRL_H    = 0x08
+    _KEY_TAB       = 0x09
+    _KEY_LF        = 0x0a
+    _KEY_CTRL_K    = 0x0b
+    _KEY_CTRL_L    = 0x0c
+    _KEY_CR        = 0x0d
+    _KEY_CTRL_N    = 0x0e
+    _KEY_CTRL_P    = 0x10
+    _KEY_CTRL_U    = 0x15
+    _KEY_ESC       = 0x1b
+    _KEY_BACKSPACE = 0x7f
+)
+
+var (
+    ErrInterrupted = MakeError(ErrIO, "terminal: interrupted")
+)
+
+type LineEditor struct {
+    Complete func(prefix string) []string
+    fd       int
+    in       *bufio.Reader
+    out      *Port
+    hist     []string
+}
+
+type _LineState struct {
+    buf    []rune
+    pos    int
+    hist   int
+    saved  []rune
+    prompt string
+}
+
+func CreateLineEditor(in *os.File, out *Port) *LineEditor {
+    return &LineEditor {
+        fd  : int(in.Fd()),
+        in  : bufio.NewReader(in),
+        out : out,
+    }
+}
+
+func (self *LineEditor) write(s string) {
+    _ = CatchError(func() { self.out.Write([]byte(s)) })
+}
+
+func (self *LineEditor) AddHistory(line string) {
+    if line = strings.TrimSpace(line); line != "" {
+        if len(self.hist) == 0 || self.hist[len(self.hist) - 1] != line {
+            self.hist = append(self.hist, line)
+        }
+    }
+}
+
+func (self *LineEditor) ReadLine(prompt string) (string, error) {
+    if restore, err := makeRaw(self.fd); err != nil {
+        return self.readPlain()
+    } else {
+        defer restore()
+        return self.readRaw(prompt)
+    }
+}
+
+func (self *LineEditor) readPlain() (string, error) {
+    if line, err := self.in.ReadString('\n'); err == nil {
+        return strings.TrimSuffix(line, "\n"), nil
+    } else if err == io.EOF && line != "" {
+        return line, nil
+    } else {
+        return "", err
+    }
+}
+
+func (self *LineEditor) readRaw(prompt string) (string, error) {
+    st := &_LineState {
+        hist   : len(self.hist),
+        prompt : prompt,
+    }
+
+    /* read and process every key */
+    for self.refresh(st);; self.refresh(st) {
+        ch, _, err := self.in.ReadRune()
+
+        /* check for read errors */
+        if err != nil {
+            self.write("\n")
+            return "", err
+        }
+
+        /* process the key */
+        switch ch {
+            case _KEY_CR, _KEY_LF  : self.write("\n"); return string(st.buf), nil
+            case _KEY_CTRL_C       : self.write("^C\n"); return "", ErrInterrupted
+            case _KEY_CTRL_A       : st.pos = 0
+            case _KEY_CTRL_E       : st.pos = len(st.buf)
+            case _KEY_CTRL_B       : st.move(-1)
+            case _KEY_CTRL_F       : st.move(1)
+            case _KEY_CTRL_P       : self.history(st, -1)
+            case _KEY_CTRL_N       : self.history(st, 1)
+            case _KEY_CTRL_K       : st.buf = st.buf[:st.pos]
+            case _KEY_CTRL_U       : st.buf, st.pos = st.buf[st.pos:], 0
+            case _KEY_CTRL_L       : self.write("\x1b[H\x1b[2J")
+            case _KEY_CTRL_H       : st.backspace()
+            case _KEY_BACKSPACE    : st.backspace()
+            case _KEY_TAB          : self.complete(st)
+            case _KEY_ESC          : self.escape(st)
+
+            /* EOF on empty lines, otherwise delete the character under cursor */
+            case _KEY_CTRL_D: {
+                if len(st.buf) == 0 {
+                    self.write("\n")
+                    return "", io.EOF
+                } else {
+                    st.delete()
+                }
+            }
+
+            /* printable characters */
+            default: {
+                if ch >= ' ' {
+                    st.insert([]rune { ch })
+                }
+            }
+        }
+    }
+}
+
+func (self *LineEditor) refresh(st *_LineState) {
+    var sb strings.Builder
+    sb.WriteString("\r")
+    sb.WriteString(st.prompt)
+    sb.WriteString(string(st.buf))
+    sb.WriteString("\x1b[K\r")
+
+    /* move the cursor to the editing position */
+    if n := len([]rune(st.prompt)) + st.pos; n != 0 {
+        sb.WriteString("\x1b[" + strconv.Itoa(n) + "C")
+    }
+
+    /* write the entire line at once to avoid flickering */
+    self.write(sb.String())
+}
+
+func (self *LineEditor) pending() (rune, bool) {
+    if self.in.Buffered() == 0 {
+        return 0, false
+    } else {
+        ch, _, err := self.in.ReadRune()
+        return ch, err == nil
+    }
+}
+
+func (self *LineEditor) escape(st *_LineState) {
+    var ok bool
+    var ch rune
+
+    /* escape sequences always arrive at once, a lone ESC is ignored rather than waiting for more keys */
+    if ch, ok = self.pending(); !ok || (ch != '[' && ch != 'O') {
+        return
+    }
+
+    /* read the final byte, parameters are digits and semicolons */
+    var arg []rune
+    for ch, ok = self.pending(); ok && (ch == ';' || (ch >= '0' && ch <= '9')); ch, ok = self.pending() {
+        arg = append(arg, ch)
+    }
+
+    /* interpret the sequence */
+    switch {
+        case !ok                             : break
+        case ch == 'A'                       : self.history(st, -1)
+        case ch == 'B'                       : self.history(st, 1)
+        case ch == 'C'                       : st.move(1)
+        case ch == 'D'                       : st.move(-1)
+        case ch == 'H'                       : st.pos = 0
+        case ch == 'F'                       : st.pos = len(st.buf)
+        case ch == '~' && string(arg) == "1" : st.pos = 0
+        case ch == '~' && string(arg) == "3" : st.delete()
+        case ch == '~' && string(arg) == "4" : st.pos = len(st.buf)
+    }
+}
+
+func (self *LineEditor) history(st *_LineState, dir int) {
+    if pos := st.hist + dir; pos >= 0 && pos <= len(self.hist) {
+        if st.hist == len(self.hist) {
+            st.saved = st.buf
+        }
+
+        /* the position after the last history entry is the line being edited */
+        if st.hist = pos; pos == len(self.hist) {
+            st.buf = st.saved
+        } else {
+            st.buf = []rune(self.hist[pos])
+        }
+
+        /* move the cursor to the end */
+        st.pos = len(st.buf)
+    }
+}
+
+func (self *LineEditor) complete(st *_LineState) {
+    if self.Complete == nil {
+        return
+    }
+
+    /* find the start of the word under cursor */
+    p := st.pos
+    for p > 0 && isAtomChar(st.buf[p - 1]) && st.buf[p - 1] != '\'' {
+        p--
+    }
+
+    /* find all the candidates */
+    word := string(st.buf[p:st.pos])
+    cands := self.Complete(word)
+
+    /* check for the candidates */
+    switch len(cands) {
+        case 0  : self.write("\a")
+        case 1  : st.insert([]rune(cands[0][len(word):]))
+        default : self.completeMany(st, word, cands)
+    }
+}
+
+func (self *LineEditor) completeMany(st *_LineState, word string, cands []string) {
+    pfx := []rune(cands[0])
+    for _, s := range cands[1:] {
+        for !strings.HasPrefix(s, string(pfx)) {
+            pfx = pfx[:len(pfx) - 1]
+        }
+    }
+
+    /* extend the word with the common prefix, or list all the candidates */
+    if nb := len([]rune(word)); len(pfx) > nb {
+        st.insert(pfx[nb:])
+    } else {
+        self.write("\n" + strings.Join(cands, "  ") + "\n")
+    }
+}
+
+func (self *_LineState) move(dir int) {
+    if pos := self.pos + dir; pos >= 0 && pos <= len(self.buf) {
+        self.pos = pos
+    }
+}
+
+func (self *_LineState) insert(v []rune) {
+    buf := make([]rune, 0, len(self.buf) + len(v))
+    buf = append(buf, self.buf[:self.pos]...)
+    buf = append(buf, v...)
+    self.buf = append(buf, self.buf[self.pos:]...)
+    self.pos += len(v)
+}
+
+func (self *_LineState) delete() {
+    if self.pos < len(self.buf) {
+        self.buf = append(self.buf[:self.pos:self.pos], self.buf[self.pos + 1:]...)
+    }
+}
+
+func (self *_LineState) backspace() {
+    if self.pos > 0 {
+        self.pos--
+        self.delete()
+    }
+}
@@ -0,0 +1,13 @@
+//go:build darwin
+// +build darwin
+
+package lisp
+
+import (
+    `syscall`
+)
+
+const (
+    _IOCTL_GETATTR = syscall.TIOCGETA
+    _IOCTL_SETATTR = syscall.TIOCSETA
+)
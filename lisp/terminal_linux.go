@@ -0,0 +1,13 @@
+//go:build linux
+// +build linux
+
+package lisp
+
+import (
+    `syscall`
+)
+
+const (
+    _IOCTL_GETATTR = syscall.TCGETS
+    _IOCTL_SETATTR = syscall.TCSETS
+)
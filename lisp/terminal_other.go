@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package lisp
+
+func makeRaw(_ int) (func(), error) {
+    return nil, MakeError(ErrIO, "terminal: raw mode is not supported")
+}
@@ -0,0 +1,56 @@
+package lisp
+
+import (
+    `bufio`
+    `io`
+    `strings`
+    `testing`
+
+    `github.com/stretchr/testify/require`
+)
+
+func editor(in io.Reader) (*LineEditor, *_TestOutput) {
+    out := new(_TestOutput)
+    return &LineEditor { in: bufio.NewReader(in), out: CreatePort("<test>", out) }, out
+}
+
+func TestLineEditor_Output(t *testing.T) {
+    it := CreateInterpreter()
+    out := new(_TestOutput)
+    it.Stdout = CreatePort("<test>", out)
+    repl := CreateREPL(it)
+    repl.ed.write("> ")
+    require.Equal(t, "> ", out.String())
+    ed, out := editor(strings.NewReader("ab\r"))
+    line, err := ed.readRaw("> ")
+    require.NoError(t, err)
+    require.Equal(t, "ab", line)
+    require.True(t, strings.HasPrefix(out.String(), "\r> "))
+}
+
+func TestLineEditor_Complete(t *testing.T) {
+    ed, out := editor(strings.NewReader("λ\t\r"))
+    ed.Complete = func(string) []string { return []string { "λx-α", "λx-β" } }
+    line, err := ed.readRaw("> ")
+    require.NoError(t, err)
+    require.Equal(t, "λx-", line)
+    ed, out = editor(strings.NewReader("a\t\r"))
+    ed.Complete = func(string) []string { return []string { "aα", "aβ" } }
+    line, err = ed.readRaw("> ")
+    require.NoError(t, err)
+    require.Equal(t, "a", line)
+    require.Contains(t, out.String(), "\naα  aβ\n")
+}
+
+func TestLineEditor_Escape(t *testing.T) {
+    rd, wr := io.Pipe()
+    ed, _ := editor(rd)
+    go func() {
+        _, _ = wr.Write([]byte("ac\x1b[D"))
+        _, _ = wr.Write([]byte("\x1b"))
+        _, _ = wr.Write([]byte("b\r"))
+    }()
+    line, err := ed.readRaw("> ")
+    require.NoError(t, err)
+    require.Equal(t, "abc", line)
+}
@@ -0,0 +1,45 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package lisp
+
+import (
+    `syscall`
+    `unsafe`
+)
+
+func ioctl(fd int, req uintptr, tio *syscall.Termios) error {
+    if _, _, err := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, uintptr(unsafe.Pointer(tio))); err != 0 {
+        return err
+    } else {
+        return nil
+    }
+}
+
+func makeRaw(fd int) (func(), error) {
+    var old syscall.Termios
+    var tio syscall.Termios
+
+    /* this also checks if the file is a terminal */
+    if err := ioctl(fd, _IOCTL_GETATTR, &old); err != nil {
+        return nil, err
+    }
+
+    /* no echoing, no line buffering, no signals, but keep the output processing */
+    tio = old
+    tio.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+    tio.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+    tio.Cflag &^= syscall.CSIZE | syscall.PARENB
+    tio.Cflag |= syscall.CS8
+
+    /* read one byte at a time */
+    tio.Cc[syscall.VMIN] = 1
+    tio.Cc[syscall.VTIME] = 0
+
+    /* switch to raw mode */
+    if err := ioctl(fd, _IOCTL_SETATTR, &tio); err != nil {
+        return nil, err
+    } else {
+        return func() { _ = ioctl(fd, _IOCTL_SETATTR, &old) }, nil
+    }
+}
@@ -1,8 +1,9 @@
-package main
+package lisp
 
 import (
     `fmt`
     `math`
+    `math/big`
     `strconv`
     `strings`
 )
@@ -23,6 +24,8 @@ type Numerical interface {
     AsInt() Int
     AsFloat() Float
     AsComplex() Complex
+    AsBigInt() *BigInt
+    AsRational() *Rational
 }
 
 func AsList(v Value) (*List, bool) {
@@ -57,11 +60,44 @@ type (
     Complex complex128
 )
 
+type (
+    BigInt   big.Int
+    Rational big.Rat
+)
+
+func MakeInteger(v *big.Int) Numerical {
+    if !v.IsInt64() {
+        return (*BigInt)(v)
+    } else {
+        return Int(v.Int64())
+    }
+}
+
+func MakeRational(v *big.Rat) Numerical {
+    if !v.IsInt() {
+        return (*Rational)(v)
+    } else {
+        return MakeInteger(v.Num())
+    }
+}
+
 type List struct {
     Car Value
     Cdr Value
 }
 
+type Vector struct {
+    Elems []Value
+}
+
+type Unspecified struct{}
+
+func MakeVector(vals []Value) *Vector {
+    return &Vector {
+        Elems: vals,
+    }
+}
+
 func MakeList(vals ...Value) *List {
     var p, q *List
     for _, v := range vals { AppendValue(&p, &q, v) }
@@ -92,10 +128,20 @@ func (Bool)    IsIdentity() bool { return true  }
 func (Char)    IsIdentity() bool { return true  }
 func (Atom)    IsIdentity() bool { return false }
 func (*List)   IsIdentity() bool { return false }
+func (*Vector) IsIdentity() bool { return true  }
 func (Float)   IsIdentity() bool { return true  }
 func (String)  IsIdentity() bool { return true  }
 func (Complex) IsIdentity() bool { return true  }
 
+func (Unspecified) IsIdentity() bool { return true }
+
+func (*BigInt)   IsIdentity() bool { return true }
+func (*Rational) IsIdentity() bool { return true }
+
+func (Unspecified) String() string {
+    return "#[unspecified]"
+}
+
 func (self Int) String() string {
     return strconv.Itoa(int(self))
 }
@@ -154,6 +200,12 @@ func (self *List) String() string {
     )
 }
 
+func (self *Vector) String() string {
+    rb := make([]string, len(self.Elems))
+    for i, v := range self.Elems { rb[i] = AsString(v) }
+    return fmt.Sprintf("#(%s)", strings.Join(rb, " "))
+}
+
 func (self Float) String() string {
     vv := strconv.FormatFloat(float64(self), 'g', -1, 64)
     vp := strings.Split(vv, "e")
@@ -168,6 +220,14 @@ func (self Float) String() string {
     return strings.Join(vp, "e")
 }
 
+func (self *BigInt) String() string {
+    return self.Int().String()
+}
+
+func (self *Rational) String() string {
+    return self.Rat().RatString()
+}
+
 func (self String) String() string {
     return strconv.Quote(string(self))
 }
@@ -187,6 +247,48 @@ func (self Int) AsInt()     Int     { return self }
 func (self Int) AsFloat()   Float   { return Float(self) }
 func (self Int) AsComplex() Complex { return Complex(complex(float64(self), 0)) }
 
+func (self Int) AsBigInt() *BigInt {
+    return (*BigInt)(big.NewInt(int64(self)))
+}
+
+func (self Int) AsRational() *Rational {
+    return (*Rational)(new(big.Rat).SetInt64(int64(self)))
+}
+
+/** Numerical Protocols for BigInt **/
+
+func (self *BigInt) Kind()       NumKind   { return NumBigInt }
+func (self *BigInt) Int()        *big.Int  { return (*big.Int)(self) }
+func (self *BigInt) AsInt()      Int       { return Int(self.Int().Int64()) }
+func (self *BigInt) AsComplex()  Complex   { return Complex(complex(float64(self.AsFloat()), 0)) }
+func (self *BigInt) AsBigInt()   *BigInt   { return self }
+func (self *BigInt) AsRational() *Rational { return (*Rational)(new(big.Rat).SetInt(self.Int())) }
+
+func (self *BigInt) AsFloat() Float {
+    fv, _ := new(big.Float).SetInt(self.Int()).Float64()
+    return Float(fv)
+}
+
+/** Numerical Protocols for Rational **/
+
+func (self *Rational) Kind()       NumKind   { return NumRational }
+func (self *Rational) Rat()        *big.Rat  { return (*big.Rat)(self) }
+func (self *Rational) AsComplex()  Complex   { return Complex(complex(float64(self.AsFloat()), 0)) }
+func (self *Rational) AsRational() *Rational { return self }
+
+func (self *Rational) AsBigInt() *BigInt {
+    return (*BigInt)(new(big.Int).Quo(self.Rat().Num(), self.Rat().Denom()))
+}
+
+func (self *Rational) AsInt() Int {
+    return self.AsBigInt().AsInt()
+}
+
+func (self *Rational) AsFloat() Float {
+    fv, _ := self.Rat().Float64()
+    return Float(fv)
+}
+
 /** Numerical Protocols for Float **/
 
 func (self Float) Kind()      NumKind { return NumFloat }
@@ -194,6 +296,18 @@ func (self Float) AsInt()     Int     { return Int(self) }
 func (self Float) AsFloat()   Float   { return self }
 func (self Float) AsComplex() Complex { return Complex(complex(float64(self), 0)) }
 
+func (self Float) AsBigInt() *BigInt {
+    return self.AsRational().AsBigInt()
+}
+
+func (self Float) AsRational() *Rational {
+    if math.IsInf(float64(self), 0) || math.IsNaN(float64(self)) {
+        panic(MakeError(ErrType, "cast: cannot convert " + self.String() + " into exact numbers"))
+    } else {
+        return (*Rational)(new(big.Rat).SetFloat64(float64(self)))
+    }
+}
+
 /** Numerical Protocols for Complex **/
 
 func (self Complex) Kind()      NumKind { return NumComplex }
@@ -201,6 +315,14 @@ func (self Complex) AsInt()     Int     { return Int(self.AsRealNumber()) }
 func (self Complex) AsFloat()   Float   { return Float(self.AsRealNumber()) }
 func (self Complex) AsComplex() Complex { return self }
 
+func (self Complex) AsBigInt() *BigInt {
+    return self.AsRational().AsBigInt()
+}
+
+func (self Complex) AsRational() *Rational {
+    return Float(self.AsRealNumber()).AsRational()
+}
+
 func (self Complex) Magnitude() Float {
     return Float(math.Hypot(
         real(complex128(self)),
@@ -210,7 +332,7 @@ func (self Complex) Magnitude() Float {
 
 func (self Complex) AsRealNumber() float64 {
     if v := complex128(self); imag(v) != 0 {
-        panic("cast: cannot convert complex numbers with non-zero imaginary part into real numbers")
+        panic(MakeError(ErrType, "cast: cannot convert complex numbers with non-zero imaginary part into real numbers"))
     } else {
         return real(v)
     }
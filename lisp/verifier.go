@@ -0,0 +1,244 @@
+package lisp
+
+import (
+    `fmt`
+)
+
+type _Verifier struct {
+    name  string
+    code  Program
+    depth []int
+    scope []int
+    queue []int
+    procs map[*Proc]bool
+}
+
+func Verify(p Program) error {
+    return CatchError(func() {
+        verifyProc("#[main]", p, nil, make(map[*Proc]bool))
+    })
+}
+
+func verifyProc(name string, code Program, scope []int, procs map[*Proc]bool) {
+    vf := &_Verifier {
+        name  : name,
+        code  : code,
+        depth : make([]int, len(code)),
+        scope : scope,
+        procs : procs,
+    }
+
+    /* empty programs are not returned properly */
+    if len(code) == 0 {
+        panic(MakeError(ErrVerify, fmt.Sprintf("verify: %s: empty program", name)))
+    }
+
+    /* the stack depth of every instruction is unknown at first */
+    for i := range vf.depth {
+        vf.depth[i] = -1
+    }
+
+    /* trace all the instructions from the entry point */
+    for vf.flow(0, 0, 0); len(vf.queue) != 0; {
+        pc := vf.queue[len(vf.queue) - 1]
+        vf.queue = vf.queue[:len(vf.queue) - 1]
+        vf.verify(pc)
+    }
+}
+
+func (self *_Verifier) error(pc int, msg string) *LispError {
+    return MakeError(ErrVerify, fmt.Sprintf("verify: %s: %s @%d: %s", msg, self.name, pc, self.code[pc].String()))
+}
+
+func (self *_Verifier) flow(from int, pc int, depth int) {
+    if pc < 0 || pc >= len(self.code) {
+        panic(self.error(from, "control flow out of program"))
+    } else if self.depth[pc] < 0 {
+        self.depth[pc] = depth
+        self.queue = append(self.queue, pc)
+    } else if self.depth[pc] != depth {
+        panic(self.error(pc, fmt.Sprintf("inconsistent stack depth %d and %d", self.depth[pc], depth)))
+    }
+}
+
+func (self *_Verifier) verify(pc int) {
+    iv := self.code[pc]
+    nb := self.depth[pc]
+
+    /* check the stack requirement and operands */
+    switch iv.Op() {
+        default: {
+            panic(self.error(pc, "invalid instruction"))
+        }
+
+        /* instructions that push one value */
+        case OP_ldconst: {
+            self.verifyConstant(pc)
+            self.flow(pc, pc + 1, nb + 1)
+        }
+
+        /* proc bodies are verified with their own lexical scope */
+        case OP_ldproc: {
+            self.verifyProc(pc, iv.Fn())
+            self.flow(pc, pc + 1, nb + 1)
+        }
+
+        /* global variables must have a name */
+        case OP_ldvar: {
+            self.verifyName(pc, iv.Sv())
+            self.flow(pc, pc + 1, nb + 1)
+        }
+
+        /* local variables must be within the lexical scope */
+        case OP_ldlocal: {
+            self.verifyAddr(pc)
+            self.flow(pc, pc + 1, nb + 1)
+        }
+
+        /* global variable stores keep the stack top */
+        case OP_define, OP_set: {
+            self.verifyName(pc, iv.Sv())
+            self.verifyStack(pc, 1)
+            self.flow(pc, pc + 1, nb)
+        }
+
+        /* local variable stores keep the stack top */
+        case OP_stlocal: {
+            self.verifyAddr(pc)
+            self.verifyStack(pc, 1)
+            self.flow(pc, pc + 1, nb)
+        }
+
+        /* unary operations */
+        case OP_car, OP_cdr: {
+            self.verifyStack(pc, 1)
+            self.flow(pc, pc + 1, nb)
+        }
+
+        /* binary operations */
+        case OP_cons: {
+            self.verifyStack(pc, 2)
+            self.flow(pc, pc + 1, nb - 1)
+        }
+
+        /* binary primitives must be named after the primitive */
+        case OP_add, OP_sub, OP_mul, OP_div, OP_eq, OP_lt, OP_gt, OP_le, OP_ge: {
+            self.verifyPrimitive(pc)
+            self.verifyStack(pc, 2)
+            self.flow(pc, pc + 1, nb - 1)
+        }
+
+        /* drop one value */
+        case OP_drop: {
+            self.verifyStack(pc, 1)
+            self.flow(pc, pc + 1, nb - 1)
+        }
+
+        /* unconditional jump */
+        case OP_goto: {
+            self.flow(pc, int(iv.Iv()), nb)
+        }
+
+        /* conditional branch always consumes the stack top */
+        case OP_if_false: {
+            self.verifyStack(pc, 1)
+            self.flow(pc, pc + 1, nb - 1)
+            self.flow(pc, int(iv.Iv()), nb - 1)
+        }
+
+        /* assertions keep the stack top when branching */
+        case OP_assert_true, OP_assert_false: {
+            self.verifyStack(pc, 1)
+            self.flow(pc, pc + 1, nb - 1)
+            self.flow(pc, int(iv.Iv()), nb)
+        }
+
+        /* apply the proc with arguments */
+        case OP_apply: {
+            self.verifyApply(pc)
+            self.flow(pc, pc + 1, nb - int(iv.Iv()) + 1)
+        }
+
+        /* tail-calls must be followed by return */
+        case OP_tailcall: {
+            if self.verifyApply(pc); !isTailCall(self.code, pc + 1) {
+                panic(self.error(pc, "tail-call is not followed by return"))
+            } else {
+                self.flow(pc, pc + 1, nb - int(iv.Iv()) + 1)
+            }
+        }
+
+        /* return exactly one value */
+        case OP_return: {
+            if nb != 1 {
+                panic(self.error(pc, fmt.Sprintf("unbalanced stack depth %d on return", nb)))
+            }
+        }
+    }
+}
+
+func (self *_Verifier) verifyName(pc int, name string) {
+    if name == "" {
+        panic(self.error(pc, "empty variable name"))
+    }
+}
+
+func (self *_Verifier) verifyAddr(pc int) {
+    if depth, index := self.code[pc].Av(); depth >= len(self.scope) || index >= self.scope[depth] {
+        panic(self.error(pc, "local variable out of scope"))
+    }
+}
+
+func (self *_Verifier) verifyStack(pc int, nb int) {
+    if self.depth[pc] < nb {
+        panic(self.error(pc, "stack underflow"))
+    }
+}
+
+func (self *_Verifier) verifyApply(pc int) {
+    if nb := int(self.code[pc].Iv()); nb == 0 {
+        panic(self.error(pc, "applying nothing"))
+    } else {
+        self.verifyStack(pc, nb)
+    }
+}
+
+func (self *_Verifier) verifyPrimitive(pc int) {
+    if pp := _PrimitiveTab[self.code[pc].Op()]; pp == nil || pp.name != self.code[pc].Sv() {
+        panic(self.error(pc, "invalid primitive operator"))
+    }
+}
+
+func (self *_Verifier) verifyConstant(pc int) {
+    if iv := self.code[pc]; iv.Iv() != 0 {
+        if fv, ok := iv.Rv().(*_Folded); !ok {
+            panic(self.error(pc, "invalid folded constant"))
+        } else {
+            self.verifyProc(pc, fv.fn)
+        }
+    }
+}
+
+func (self *_Verifier) verifyProc(pc int, fn *Proc) {
+    if fn == nil {
+        panic(MakeError(ErrVerify, fmt.Sprintf("verify: missing proc: %s @%d", self.name, pc)))
+    }
+
+    /* the rest argument occupies one more slot */
+    nb := len(fn.Args)
+    if fn.IsVariadic() {
+        nb++
+    }
+
+    /* arguments must have their slots, and a proc can never enclose itself */
+    if fn.Slots < nb {
+        panic(self.error(pc, "not enough slots for arguments"))
+    } else if self.procs[fn] {
+        panic(self.error(pc, "recursive proc reference"))
+    }
+
+    /* verify the proc body within the enclosing scope */
+    self.procs[fn] = true
+    verifyProc(fn.Name, fn.Code, append([]int{fn.Slots}, self.scope...), self.procs)
+    delete(self.procs, fn)
+}
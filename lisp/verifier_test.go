@@ -0,0 +1,58 @@
+package lisp
+
+import (
+    `testing`
+
+    `github.com/stretchr/testify/require`
+)
+
+func TestVerifier_Valid(t *testing.T) {
+    src := `
+        (define (f x . r)
+            (let ((y (car r)))
+                (if (and x y) (+ x y) (g (lambda () x)))))
+    `
+    require.NoError(t, Verify(Compiler{}.Compile(CreateParser(src).Parse())))
+    require.NoError(t, Verify(Compiler{NoOptimize: true}.Compile(CreateParser(src).Parse())))
+}
+
+func TestVerifier_Invalid(t *testing.T) {
+    var p1, p2, p3, p4, p5, p6, p7, p8, p9 Program
+    p1.add(OP_cons)
+    p1.add(OP_return)
+    p2.val(OP_ldconst, Int(1))
+    p2.jmp(OP_goto, 5)
+    p2.add(OP_return)
+    p3.val(OP_ldconst, Int(1))
+    p3.val(OP_ldconst, Int(2))
+    p3.add(OP_return)
+    p4.str(OP_ldvar, "f")
+    p4.i32(OP_tailcall, 1)
+    p4.add(OP_drop)
+    p4.val(OP_ldconst, nil)
+    p4.add(OP_return)
+    p5.val(OP_ldconst, Int(1))
+    p5.val(OP_ldconst, Bool(true))
+    p5.jmp(OP_if_false, 4)
+    p5.add(OP_drop)
+    p5.add(OP_return)
+    p6.i32(OP_ldlocal, 0)
+    p6.add(OP_return)
+    p7.val(OP_ldconst, Int(1))
+    fn := &Proc { Name: "f" }
+    fn.Code.fnp(OP_ldproc, fn)
+    fn.Code.add(OP_return)
+    p8.fnp(OP_ldproc, fn)
+    p8.add(OP_return)
+    p9 = append(p9, mkins(OP_ldconst, 1, "", Int(1), nil))
+    p9.add(OP_return)
+    require.EqualError(t, Verify(p1), "verify: stack underflow: #[main] @0: cons")
+    require.EqualError(t, Verify(p2), "verify: control flow out of program: #[main] @1: goto        @5")
+    require.EqualError(t, Verify(p3), "verify: unbalanced stack depth 2 on return: #[main] @2: return")
+    require.EqualError(t, Verify(p4), "verify: tail-call is not followed by return: #[main] @1: tailcall    #1")
+    require.EqualError(t, Verify(p5), "verify: inconsistent stack depth 1 and 0: #[main] @4: return")
+    require.EqualError(t, Verify(p6), "verify: local variable out of scope: #[main] @0: ldlocal     [0, 0]")
+    require.EqualError(t, Verify(p7), "verify: control flow out of program: #[main] @0: ldconst     (int) 1")
+    require.EqualError(t, Verify(p8), "verify: recursive proc reference: f @0: ldproc      " + fn.String())
+    require.EqualError(t, Verify(p9), "verify: invalid folded constant: #[main] @0: ldconst     (int) 1")
+}
@@ -3,38 +3,303 @@ package main
 import (
     `fmt`
     `os`
+    `strings`
+
+    `github.com/chenzhuoyu/simple-lisp/lisp`
 )
 
-func readfile(fname string) string {
-    var nbr int
+func loadsrc(it *lisp.Interpreter, src _Source) error {
+    switch src.kind {
+        case _SRC_file  : return it.Load(src.text)
+        case _SRC_expr  : return it.LoadFrom("<expr>", strings.NewReader(src.text))
+        case _SRC_stdin : return it.LoadFrom(src.text, os.Stdin)
+        default         : panic("unreachable")
+    }
+}
+
+func compilefile(fname string, oname string) error {
     var err error
     var rfp *os.File
+    var ret []lisp.Program
 
     /* open the file */
     if rfp, err = os.OpenFile(fname, os.O_RDONLY, 0); err != nil {
-        panic(fmt.Sprintf("io: unable to open %s: %s", fname, err))
+        return lisp.MakeError(lisp.ErrIO, fmt.Sprintf("io: unable to open %s: %s", fname, err))
     }
 
-    /* allocate memory for file */
-    buf := make([]byte, MaxBufferSize)
-    ret := make([]byte, 0, MaxBufferSize)
+    /* compile the file */
+    ret, err = lisp.CreateInterpreter().Compile(fname, rfp)
+    rfp.Close()
+
+    /* check for errors */
+    if err != nil {
+        return err
+    }
 
-    /* read all data */
-    for err == nil {
-        if nbr, err = rfp.Read(buf); nbr != 0 {
-            ret = append(ret, buf[:nbr]...)
+    /* replace the source extension if no output file was given */
+    if oname == "" {
+        if oname = fname; strings.HasSuffix(fname, ".scm") {
+            oname = fname[:len(fname) - 4]
         }
+        oname += lisp.BytecodeExt
     }
 
-    /* close the file, and convert the result to string */
-    _ = rfp.Close()
-    return string(ret)
+    /* serialize the bytecode */
+    return lisp.WriteBytecodeFile(oname, ret)
 }
 
-func main() {
-    if len(os.Args) != 2 || os.Args[1] == "-h" {
-        println(fmt.Sprintf("usage: %s [-h] [file-name]", os.Args[0]))
+/** Command Line **/
+
+const (
+    ExitError = 1
+    ExitUsage = 2
+)
+
+type _SourceKind uint8
+
+const (
+    _SRC_file _SourceKind = iota
+    _SRC_expr
+    _SRC_stdin
+)
+
+type _Source struct {
+    kind _SourceKind
+    text string
+}
+
+type _Options struct {
+    help   bool
+    lines  bool
+    disasm bool
+    noopt  bool
+    srcs   []_Source
+    args   []string
+}
+
+func usage() {
+    println(fmt.Sprintf("usage: %s [-h] [--no-optimize] [-e <expr>] [-] [file-name ...] [-- args ...]", os.Args[0]))
+    println(fmt.Sprintf("       %s --disasm [--lines] [--no-optimize] [-e <expr>] [-] [file-name ...]", os.Args[0]))
+    println(fmt.Sprintf("       %s compile <file-name> [-o <output-file>]", os.Args[0]))
+    println()
+    println("Loads all the sources in order, or starts a REPL if none were given. If the first")
+    println("source is a script starting with \"#!\", the remaining arguments are passed to it.")
+    println()
+    println("options:")
+    println("    -h            show this help message and exit")
+    println("    -e <expr>     evaluate the expression")
+    println("    -             read the program from standard input")
+    println("    --            pass the remaining arguments to the program, see (command-line)")
+    println("    --disasm      print the disassembly of the sources instead of running them")
+    println("    --lines       annotate the disassembly with source lines")
+    println("    --no-optimize disable the bytecode optimizer")
+}
+
+func oneline(err error) string {
+    return strings.ReplaceAll(err.Error(), "\n", "\\n")
+}
+
+func isScript(fname string) bool {
+    var nb int
+    var err error
+    var rfp *os.File
+    var buf [2]byte
+
+    /* open the file, errors are reported when loading it */
+    if rfp, err = os.OpenFile(fname, os.O_RDONLY, 0); err != nil {
+        return false
+    }
+
+    /* scripts are files starting with "#!" */
+    defer rfp.Close()
+    nb, _ = rfp.Read(buf[:])
+    return nb == 2 && string(buf[:]) == "#!"
+}
+
+func parseArgs(argv []string) (*_Options, error) {
+    ret := new(_Options)
+    buf := argv
+
+    /* parse every argument */
+    for len(buf) != 0 {
+        arg := buf[0]
+        buf = buf[1:]
+
+        /* check for options */
+        switch {
+            case arg == "-h"                  : ret.help = true
+            case arg == "--lines"             : ret.lines = true
+            case arg == "--disasm"            : ret.disasm = true
+            case arg == "--no-optimize"       : ret.noopt = true
+            case arg == "-"                   : ret.srcs = append(ret.srcs, _Source { kind: _SRC_stdin, text: "<stdin>" })
+            case arg == "--"                  : ret.args, buf = buf, nil
+
+            /* arguments after a shebang script belong to the script */
+            case !strings.HasPrefix(arg, "-"): {
+                if ret.srcs = append(ret.srcs, _Source { kind: _SRC_file, text: arg }); len(ret.srcs) == 1 && isScript(arg) {
+                    ret.args, buf = buf, nil
+                }
+            }
+
+            /* expressions take one argument */
+            case arg == "-e": {
+                if len(buf) == 0 {
+                    return nil, lisp.MakeError(lisp.ErrUser, "option -e requires an expression")
+                } else {
+                    ret.srcs = append(ret.srcs, _Source { kind: _SRC_expr, text: buf[0] })
+                    buf = buf[1:]
+                }
+            }
+
+            /* unknown options */
+            default: {
+                return nil, lisp.MakeError(lisp.ErrUser, "unknown option", lisp.String(arg))
+            }
+        }
+    }
+
+    /* check for option combinations */
+    if ret.lines && !ret.disasm {
+        return nil, lisp.MakeError(lisp.ErrUser, "option --lines requires --disasm")
+    } else if ret.disasm && len(ret.srcs) == 0 {
+        return nil, lisp.MakeError(lisp.ErrUser, "option --disasm requires at least one source")
+    } else {
+        return ret, nil
+    }
+}
+
+func parseCompileArgs(argv []string) (string, string, error) {
+    switch {
+        case len(argv) == 1                    : return argv[0], "", nil
+        case len(argv) == 3 && argv[1] == "-o" : return argv[0], argv[2], nil
+        default                                : return "", "", lisp.MakeError(lisp.ErrUser, "compile: invalid arguments")
+    }
+}
+
+func disasmfile(it *lisp.Interpreter, fname string) ([]lisp.Program, error) {
+    var err error
+    var rfp *os.File
+
+    /* compiled bytecode files are disassembled directly */
+    if strings.HasSuffix(fname, lisp.BytecodeExt) {
+        return lisp.ReadBytecodeFile(fname)
+    }
+
+    /* open the file */
+    if rfp, err = os.OpenFile(fname, os.O_RDONLY, 0); err != nil {
+        return nil, lisp.MakeError(lisp.ErrIO, fmt.Sprintf("io: unable to open %s: %s", fname, err))
+    }
+
+    /* compile the file, and close it after compiling */
+    defer rfp.Close()
+    return it.Compile(fname, rfp)
+}
+
+func disasmsrcs(opts *_Options) error {
+    it := lisp.CreateInterpreter()
+    buf := []string(nil)
+
+    /* optionally disable the optimizer */
+    it.NoOptimize = opts.noopt
+
+    /* compile all the sources in order, without running them */
+    for _, src := range opts.srcs {
+        var err error
+        var ret []lisp.Program
+
+        /* compile the source */
+        switch src.kind {
+            case _SRC_file  : ret, err = disasmfile(it, src.text)
+            case _SRC_expr  : ret, err = it.Compile("<expr>", strings.NewReader(src.text))
+            case _SRC_stdin : ret, err = it.Compile(src.text, os.Stdin)
+        }
+
+        /* stop at the first error */
+        if err != nil {
+            return err
+        }
+
+        /* disassemble every top-level program */
+        for _, p := range ret {
+            buf = append(buf, p.Disassemble(opts.lines))
+        }
+    }
+
+    /* print the disassembly */
+    it.Stdout.Write([]byte(strings.Join(buf, "\n") + "\n"))
+    return nil
+}
+
+func run(opts *_Options) error {
+    it := lisp.CreateInterpreter()
+    it.NoOptimize = opts.noopt
+
+    /* the first element is the name of the program */
+    if len(opts.srcs) != 0 && opts.srcs[0].kind == _SRC_file {
+        it.CommandLine = append([]string { opts.srcs[0].text }, opts.args...)
     } else {
-        Evaluate(CreateGlobalScope(), Compiler{}.Compile(CreateParser(readfile(os.Args[1])).Parse()))
+        it.CommandLine = append([]string { os.Args[0] }, opts.args...)
+    }
+
+    /* start a REPL if there are no sources */
+    if len(opts.srcs) == 0 {
+        return lisp.CreateREPL(it).Run()
+    }
+
+    /* load all the sources in order, stop at the first error */
+    for _, src := range opts.srcs {
+        if err := loadsrc(it, src); err != nil {
+            return err
+        }
+    }
+
+    /* all done */
+    return nil
+}
+
+func main() {
+    var err error
+    var opts *_Options
+    var argv = os.Args[1:]
+
+    /* compile a source file into bytecode */
+    if len(argv) != 0 && argv[0] == "compile" {
+        if src, out, err := parseCompileArgs(argv[1:]); err != nil {
+            println(oneline(err) + ", see -h for usage")
+            os.Exit(ExitUsage)
+        } else if err = compilefile(src, out); err != nil {
+            println(oneline(err))
+            os.Exit(ExitError)
+        } else {
+            return
+        }
+    }
+
+    /* parse the command line */
+    if opts, err = parseArgs(argv); err != nil {
+        println(oneline(err) + ", see -h for usage")
+        os.Exit(ExitUsage)
+    }
+
+    /* check for help */
+    if opts.help {
+        usage()
+        return
+    }
+
+    /* disassemble the sources */
+    if opts.disasm {
+        if err = disasmsrcs(opts); err != nil {
+            println(oneline(err))
+            os.Exit(ExitError)
+        } else {
+            return
+        }
+    }
+
+    /* run the program */
+    if err = run(opts); err != nil {
+        println(oneline(err))
+        os.Exit(ExitError)
     }
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+    `io/ioutil`
+    `path/filepath`
+    `strconv`
+    `testing`
+
+    `github.com/stretchr/testify/require`
+)
+
+func TestMain_ParseArgs(t *testing.T) {
+    opts, err := parseArgs([]string { "a.scm", "-e", "(f)", "-", "b.scm", "--", "-e", "x" })
+    require.NoError(t, err)
+    require.Equal(t, []_Source {
+        { kind: _SRC_file, text: "a.scm" },
+        { kind: _SRC_expr, text: "(f)" },
+        { kind: _SRC_stdin, text: "<stdin>" },
+        { kind: _SRC_file, text: "b.scm" },
+    }, opts.srcs)
+    require.Equal(t, []string { "-e", "x" }, opts.args)
+    _, err = parseArgs([]string { "-e" })
+    require.EqualError(t, err, "option -e requires an expression")
+    opts, err = parseArgs([]string { "--disasm", "--no-optimize", "a.scm" })
+    require.NoError(t, err)
+    require.True(t, opts.disasm)
+    require.True(t, opts.noopt)
+    _, err = parseArgs([]string { "--what" })
+    require.EqualError(t, err, `unknown option: "--what"`)
+}
+
+func TestMain_Script(t *testing.T) {
+    dir := t.TempDir()
+    out := filepath.Join(dir, "out.txt")
+    src := filepath.Join(dir, "script.scm")
+    lib := filepath.Join(dir, "lib.scm")
+    require.NoError(t, ioutil.WriteFile(lib, []byte("(define x 1)"), 0644))
+    require.NoError(t, ioutil.WriteFile(src, []byte("#!/usr/bin/env simple-lisp\n" +
+        "(call-with-output-file " + strconv.Quote(out) + " (lambda (p) (display (command-line) p)))"), 0755))
+    opts, err := parseArgs([]string { src, "foo", "-e", "bar" })
+    require.NoError(t, err)
+    require.Equal(t, []_Source {{ kind: _SRC_file, text: src }}, opts.srcs)
+    require.Equal(t, []string { "foo", "-e", "bar" }, opts.args)
+    require.NoError(t, run(opts))
+    buf, err := ioutil.ReadFile(out)
+    require.NoError(t, err)
+    require.Equal(t, "(" + strconv.Quote(src) + ` "foo" "-e" "bar")`, string(buf))
+    opts, err = parseArgs([]string { lib, src, "foo" })
+    require.NoError(t, err)
+    require.Equal(t, 3, len(opts.srcs))
+    require.Empty(t, opts.args)
+}